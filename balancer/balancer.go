@@ -0,0 +1,205 @@
+package balancer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/virajago/go-scs-eureka"
+)
+
+// ErrNoInstances is returned if no instance with status UP could be found.
+var ErrNoInstances = errors.New("No instances available")
+
+// Resolver looks up the instances registered for a name, e.g. an app name
+// or a VIP address.
+type Resolver func(name string) ([]*eureka.Instance, error)
+
+// ClientApps resolves app names by querying Eureka through the client.
+func ClientApps(client *eureka.Client) Resolver {
+	return func(name string) ([]*eureka.Instance, error) {
+		app, err := client.App(name)
+		if err != nil {
+			return nil, err
+		}
+		return app.Instances, nil
+	}
+}
+
+// ClientVIPs resolves VIP addresses by querying Eureka through the client.
+func ClientVIPs(client *eureka.Client) Resolver {
+	return client.VIP
+}
+
+// CacheApps resolves app names from a registry cache.
+func CacheApps(cache *eureka.Cache) Resolver {
+	return func(name string) ([]*eureka.Instance, error) {
+		if app, found := cache.App(name); found {
+			return app.Instances, nil
+		}
+		return nil, nil
+	}
+}
+
+// CacheVIPs resolves VIP addresses from a registry cache.
+func CacheVIPs(cache *eureka.Cache) Resolver {
+	return func(name string) ([]*eureka.Instance, error) {
+		return cache.VIP(name), nil
+	}
+}
+
+// Option can be used to configure a Balancer.
+type Option func(*Balancer)
+
+// PreferIPAddr instructs the balancer to build URLs using the IP address
+// rather than the host name of an instance.
+func PreferIPAddr() Option {
+	return func(b *Balancer) {
+		b.preferIP = true
+	}
+}
+
+// ZoneAffinity instructs the balancer to prefer instances located in the given
+// zone. Instances in other zones are only considered if fewer than
+// minInstances instances in the zone are UP.
+func ZoneAffinity(zone string, minInstances int) Option {
+	return func(b *Balancer) {
+		b.zone = zone
+		b.minZoneInstances = minInstances
+	}
+}
+
+// Balancer picks instances for a name using a policy. Only instances with
+// status UP are considered.
+type Balancer struct {
+	resolve          Resolver
+	policy           Policy
+	preferIP         bool
+	zone             string
+	minZoneInstances int
+}
+
+// New returns a balancer that resolves names using resolve and picks instances
+// according to policy.
+func New(resolve Resolver, policy Policy, options ...Option) *Balancer {
+	b := &Balancer{
+		resolve: resolve,
+		policy:  policy,
+	}
+
+	for _, opt := range options {
+		opt(b)
+	}
+
+	return b
+}
+
+// Pick resolves the name and picks one of the instances that are UP.
+func (b *Balancer) Pick(name string) (*eureka.Instance, error) {
+	return b.pick(name, nil)
+}
+
+// pick is like Pick but skips the instances whose keys are in exclude.
+func (b *Balancer) pick(name string, exclude map[string]bool) (*eureka.Instance, error) {
+	instances, err := b.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*eureka.Instance, 0, len(instances))
+	for _, i := range Up(instances) {
+		if !exclude[key(i)] {
+			candidates = append(candidates, i)
+		}
+	}
+
+	if b.zone != "" {
+		candidates = PreferZone(candidates, b.zone, b.minZoneInstances)
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w for '%s'", ErrNoInstances, name)
+	}
+
+	return b.policy(candidates), nil
+}
+
+// BaseURL picks an instance for the name and returns its base URL.
+func (b *Balancer) BaseURL(name string, secure bool) (*url.URL, error) {
+	instance, err := b.Pick(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.URL(instance, secure), nil
+}
+
+// URL returns the base URL of an instance, honoring the balancer's
+// preference for IP addresses.
+func (b *Balancer) URL(instance *eureka.Instance, secure bool) *url.URL {
+	if b.preferIP {
+		return baseURL(instance.IPAddr, instance, secure)
+	}
+	return BaseURL(instance, secure)
+}
+
+// Up filters the instances whose status is UP.
+func Up(instances []*eureka.Instance) []*eureka.Instance {
+	up := make([]*eureka.Instance, 0, len(instances))
+	for _, i := range instances {
+		if i.Status == eureka.StatusUp {
+			up = append(up, i)
+		}
+	}
+	return up
+}
+
+// PreferZone returns the instances located in the given zone, or all
+// instances if fewer than minInstances of them are located in the zone.
+func PreferZone(instances []*eureka.Instance, zone string, minInstances int) []*eureka.Instance {
+	if minInstances < 1 {
+		minInstances = 1
+	}
+
+	local := make([]*eureka.Instance, 0, len(instances))
+	for _, i := range instances {
+		if i.Zone() == zone {
+			local = append(local, i)
+		}
+	}
+
+	if len(local) < minInstances {
+		return instances
+	}
+
+	return local
+}
+
+// BaseURL returns the base URL of an instance, e.g. http://host:8080. The host
+// name is used if present, the IP address otherwise. The secure port and https
+// are used if secure is set.
+func BaseURL(instance *eureka.Instance, secure bool) *url.URL {
+	host := instance.HostName
+	if host == "" {
+		host = instance.IPAddr
+	}
+	return baseURL(host, instance, secure)
+}
+
+func baseURL(host string, instance *eureka.Instance, secure bool) *url.URL {
+	scheme, port := "http", instance.Port
+	if secure {
+		scheme, port = "https", instance.SecurePort
+	}
+
+	if port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+
+	return &url.URL{
+		Scheme: scheme,
+		Host:   host,
+	}
+}
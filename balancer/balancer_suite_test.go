@@ -0,0 +1,13 @@
+package balancer_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBalancer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "balancer")
+}
@@ -0,0 +1,188 @@
+package balancer_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/virajago/go-scs-eureka"
+	"github.com/virajago/go-scs-eureka/balancer"
+)
+
+var _ = Describe("balancer", func() {
+	var (
+		one, two, down *eureka.Instance
+		instances      []*eureka.Instance
+
+		resolve balancer.Resolver = func(name string) ([]*eureka.Instance, error) {
+			return instances, nil
+		}
+	)
+
+	BeforeEach(func() {
+		one = &eureka.Instance{ID: "one", AppName: "APP", HostName: "one.example.com", IPAddr: "10.0.0.1", Port: 8080, SecurePort: 8443}
+		two = &eureka.Instance{ID: "two", AppName: "APP", HostName: "two.example.com", IPAddr: "10.0.0.2", Port: 8080}
+		down = &eureka.Instance{ID: "down", AppName: "APP", Status: eureka.StatusDown}
+
+		instances = []*eureka.Instance{one, down, two}
+	})
+
+	Describe(".Pick", func() {
+		It("skips instances that are not UP", func() {
+			b := balancer.New(resolve, balancer.RoundRobin())
+			for n := 0; n < 10; n++ {
+				i, err := b.Pick("app")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(i).ToNot(Equal(down))
+			}
+		})
+
+		It("returns ErrNoInstances if no instance is UP", func() {
+			instances = []*eureka.Instance{down}
+			b := balancer.New(resolve, balancer.RoundRobin())
+
+			_, err := b.Pick("app")
+			Expect(errors.Is(err, balancer.ErrNoInstances)).To(BeTrue())
+		})
+
+		It("returns resolver errors", func() {
+			someErr := errors.New("some error")
+			b := balancer.New(func(_ string) ([]*eureka.Instance, error) {
+				return nil, someErr
+			}, balancer.RoundRobin())
+
+			_, err := b.Pick("app")
+			Expect(err).To(Equal(someErr))
+		})
+	})
+
+	Describe(".ZoneAffinity", func() {
+		var local, remote *eureka.Instance
+
+		BeforeEach(func() {
+			local = &eureka.Instance{ID: "local", AppName: "APP"}
+			local.DataCenterInfo.Metadata.AvailabilityZone = "zone-a"
+			remote = &eureka.Instance{ID: "remote", AppName: "APP", Metadata: eureka.Metadata{"zone": "zone-b"}}
+
+			instances = []*eureka.Instance{remote, local}
+		})
+
+		It("prefers instances in the same zone", func() {
+			b := balancer.New(resolve, balancer.RoundRobin(), balancer.ZoneAffinity("zone-a", 1))
+			for n := 0; n < 10; n++ {
+				Expect(b.Pick("app")).To(Equal(local))
+			}
+		})
+
+		It("falls back to other zones below the threshold", func() {
+			b := balancer.New(resolve, balancer.RoundRobin(), balancer.ZoneAffinity("zone-a", 2))
+
+			picked := map[string]bool{}
+			for n := 0; n < 10; n++ {
+				i, err := b.Pick("app")
+				Expect(err).ToNot(HaveOccurred())
+				picked[i.ID] = true
+			}
+			Expect(picked).To(HaveLen(2))
+		})
+
+		It("does not count instances that are not UP", func() {
+			local.Status = eureka.StatusDown
+			b := balancer.New(resolve, balancer.RoundRobin(), balancer.ZoneAffinity("zone-a", 1))
+			Expect(b.Pick("app")).To(Equal(remote))
+		})
+	})
+
+	Describe(".BaseURL", func() {
+		It("uses the host name and port", func() {
+			instances = []*eureka.Instance{one}
+			u, err := balancer.New(resolve, balancer.RoundRobin()).BaseURL("app", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.String()).To(Equal("http://one.example.com:8080"))
+		})
+
+		It("uses https and the secure port for secure URLs", func() {
+			instances = []*eureka.Instance{one}
+			u, err := balancer.New(resolve, balancer.RoundRobin()).BaseURL("app", true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.String()).To(Equal("https://one.example.com:8443"))
+		})
+
+		It("uses the IP address if preferred", func() {
+			instances = []*eureka.Instance{one}
+			u, err := balancer.New(resolve, balancer.RoundRobin(), balancer.PreferIPAddr()).BaseURL("app", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.String()).To(Equal("http://10.0.0.1:8080"))
+		})
+
+		It("falls back to the IP address without a host name", func() {
+			one.HostName = ""
+			Expect(balancer.BaseURL(one, false).String()).To(Equal("http://10.0.0.1:8080"))
+		})
+	})
+
+	Describe("policies", func() {
+		up := func() []*eureka.Instance {
+			return []*eureka.Instance{one, two}
+		}
+
+		Describe(".RoundRobin", func() {
+			It("cycles through the instances", func() {
+				policy := balancer.RoundRobin()
+				Expect(policy(up())).To(Equal(one))
+				Expect(policy(up())).To(Equal(two))
+				Expect(policy(up())).To(Equal(one))
+			})
+		})
+
+		Describe(".Random", func() {
+			It("picks all instances eventually", func() {
+				policy := balancer.Random()
+				picked := map[string]bool{}
+				for n := 0; n < 100; n++ {
+					picked[policy(up()).ID] = true
+				}
+				Expect(picked).To(HaveLen(2))
+			})
+		})
+
+		Describe(".LeastRecentlyUsed", func() {
+			It("picks the instance that has not been used for the longest time", func() {
+				policy := balancer.LeastRecentlyUsed()
+				Expect(policy(up())).To(Equal(one))
+				Expect(policy(up())).To(Equal(two))
+				Expect(policy(up())).To(Equal(one))
+
+				three := &eureka.Instance{ID: "three", AppName: "APP"}
+				Expect(policy(append(up(), three))).To(Equal(three))
+				Expect(policy(append(up(), three))).To(Equal(two))
+			})
+		})
+
+		Describe(".Weighted", func() {
+			It("picks instances proportionally to their weight", func() {
+				one.Metadata = eureka.Metadata{"weight": "9"}
+				two.Metadata = eureka.Metadata{"weight": "1"}
+
+				policy := balancer.Weighted("weight")
+				counts := map[string]int{}
+				for n := 0; n < 1000; n++ {
+					counts[policy(up()).ID]++
+				}
+
+				Expect(counts["one"]).To(BeNumerically("~", 900, 60))
+				Expect(counts["two"]).To(BeNumerically("~", 100, 60))
+			})
+
+			It("never picks instances with zero weight", func() {
+				one.Metadata = eureka.Metadata{"weight": "0"}
+
+				policy := balancer.Weighted("weight")
+				for n := 0; n < 100; n++ {
+					Expect(policy(up())).To(Equal(two))
+				}
+			})
+		})
+	})
+})
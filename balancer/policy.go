@@ -0,0 +1,147 @@
+package balancer
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/virajago/go-scs-eureka"
+)
+
+// Policy picks one of the given instances. It is never called with an empty
+// list and must be safe for concurrent use.
+type Policy func(instances []*eureka.Instance) *eureka.Instance
+
+// RoundRobin cycles through the instances.
+func RoundRobin() Policy {
+	var (
+		mtx  sync.Mutex
+		next uint
+	)
+
+	return func(instances []*eureka.Instance) *eureka.Instance {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		i := instances[next%uint(len(instances))]
+		next++
+		return i
+	}
+}
+
+// Random picks instances at random.
+func Random() Policy {
+	rnd := newLockedRand()
+
+	return func(instances []*eureka.Instance) *eureka.Instance {
+		return instances[rnd.Intn(len(instances))]
+	}
+}
+
+// LeastRecentlyUsed picks the instance that has not been picked for the
+// longest time. Instances that have never been picked come first.
+func LeastRecentlyUsed() Policy {
+	var (
+		mtx      sync.Mutex
+		lastUsed = map[string]time.Time{}
+	)
+
+	return func(instances []*eureka.Instance) *eureka.Instance {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		var (
+			pick   *eureka.Instance
+			oldest time.Time
+		)
+
+		for _, i := range instances {
+			used, found := lastUsed[key(i)]
+			if !found {
+				pick = i
+				break
+			}
+
+			if pick == nil || used.Before(oldest) {
+				pick, oldest = i, used
+			}
+		}
+
+		lastUsed[key(pick)] = time.Now()
+		return pick
+	}
+}
+
+// Weighted picks instances at random, proportionally to the integer weight
+// stored in their metadata under the given key. Instances without a valid
+// weight have a weight of 1. If all weights are zero, instances are picked
+// uniformly.
+func Weighted(metadataKey string) Policy {
+	rnd := newLockedRand()
+
+	return func(instances []*eureka.Instance) *eureka.Instance {
+		weights := make([]int, len(instances))
+		total := 0
+
+		for n, i := range instances {
+			weights[n] = weight(i, metadataKey)
+			total += weights[n]
+		}
+
+		if total == 0 {
+			return instances[rnd.Intn(len(instances))]
+		}
+
+		r := rnd.Intn(total)
+		for n, w := range weights {
+			if r < w {
+				return instances[n]
+			}
+			r -= w
+		}
+
+		return instances[len(instances)-1]
+	}
+}
+
+func weight(i *eureka.Instance, metadataKey string) int {
+	value, found := i.Metadata[metadataKey]
+	if !found {
+		return 1
+	}
+
+	w, err := strconv.Atoi(value)
+	if err != nil {
+		return 1
+	}
+
+	if w < 0 {
+		return 0
+	}
+
+	return w
+}
+
+func key(i *eureka.Instance) string {
+	// instance ids might not be unique across apps
+	return i.AppName + "-" + i.ID
+}
+
+type lockedRand struct {
+	mtx sync.Mutex
+	rnd *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	return &lockedRand{
+		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (r *lockedRand) Intn(n int) int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.rnd.Intn(n)
+}
@@ -0,0 +1,136 @@
+package balancer
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/virajago/go-scs-eureka"
+)
+
+// DefaultTransportAttempts defines the default number of instances a request
+// is tried against before giving up.
+const DefaultTransportAttempts = 3
+
+// TransportOption can be used to configure a Transport.
+type TransportOption func(*Transport)
+
+// TransportBase sets the round tripper used to send the rewritten requests.
+// Defaults to http.DefaultTransport.
+func TransportBase(base http.RoundTripper) TransportOption {
+	return func(t *Transport) {
+		t.base = base
+	}
+}
+
+// TransportAttempts sets the maximum number of instances a request is tried
+// against if connecting to an instance fails.
+func TransportAttempts(attempts int) TransportOption {
+	return func(t *Transport) {
+		t.attempts = attempts
+	}
+}
+
+// Transport is an http.RoundTripper that treats the host of a request URL as
+// the name of an app or VIP, e.g. http://ORDERS-SERVICE/api/orders. It picks
+// an instance using its balancer and sends the request to that instance,
+// using the secure port for https URLs. If the connection to an instance
+// fails, the request is retried against a different instance.
+type Transport struct {
+	balancer *Balancer
+	base     http.RoundTripper
+	attempts int
+}
+
+// NewTransport returns a transport that picks instances using b.
+func NewTransport(b *Balancer, options ...TransportOption) *Transport {
+	t := &Transport{
+		balancer: b,
+		base:     http.DefaultTransport,
+		attempts: DefaultTransportAttempts,
+	}
+
+	for _, opt := range options {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		name    = req.URL.Hostname()
+		secure  = req.URL.Scheme == "https"
+		tried   = map[string]bool{}
+		lastErr error
+	)
+
+	for attempt := 0; attempt < t.attempts; attempt++ {
+		instance, err := t.balancer.pick(name, tried)
+		if err != nil {
+			closeBody(req)
+			if lastErr != nil {
+				// ran out of instances, report why the last one failed
+				return nil, lastErr
+			}
+			return nil, err
+		}
+		tried[key(instance)] = true
+
+		out, err := t.rewrite(req, instance, secure, attempt)
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(out)
+		if err == nil {
+			return resp, nil
+		}
+
+		lastErr = err
+		if !isConnectError(err) || !replayable(req) || req.Context().Err() != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+
+	closeBody(req)
+	return nil, lastErr
+}
+
+func (t *Transport) rewrite(req *http.Request, instance *eureka.Instance, secure bool, attempt int) (*http.Request, error) {
+	out := req.Clone(req.Context())
+
+	base := t.balancer.URL(instance, secure)
+	out.URL.Scheme = base.Scheme
+	out.URL.Host = base.Host
+	out.Host = base.Host
+
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+
+	return out, nil
+}
+
+// isConnectError reports whether the request failed before it reached the
+// instance, in which case it is safe to send it to a different instance.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,189 @@
+package balancer_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/virajago/go-scs-eureka"
+	"github.com/virajago/go-scs-eureka/balancer"
+)
+
+var _ = Describe("Transport", func() {
+	var (
+		server    *httptest.Server
+		live      *eureka.Instance
+		dead      *eureka.Instance
+		instances []*eureka.Instance
+		resolved  []string
+		client    *http.Client
+	)
+
+	instanceFor := func(id, addr string) *eureka.Instance {
+		host, port, err := net.SplitHostPort(addr)
+		Expect(err).ToNot(HaveOccurred())
+		p, err := strconv.Atoi(port)
+		Expect(err).ToNot(HaveOccurred())
+		return &eureka.Instance{ID: id, AppName: "ORDERS", IPAddr: host, Port: eureka.Port(p), SecurePort: eureka.Port(p + 1)}
+	}
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write([]byte(r.URL.Path + " " + string(body)))
+		}))
+		live = instanceFor("live", server.Listener.Addr().String())
+
+		// grab a free port and close it again to simulate a dead instance
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		dead = instanceFor("dead", l.Addr().String())
+		l.Close()
+
+		instances = []*eureka.Instance{live}
+		resolved = nil
+
+		resolve := func(name string) ([]*eureka.Instance, error) {
+			resolved = append(resolved, name)
+			return instances, nil
+		}
+
+		client = &http.Client{
+			Transport: balancer.NewTransport(balancer.New(resolve, balancer.RoundRobin())),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("resolves the host and sends the request to an instance", func() {
+		resp, err := client.Get("http://ORDERS/api/orders")
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("/api/orders "))
+		Expect(resolved).To(Equal([]string{"ORDERS"}))
+	})
+
+	It("retries the request on a different instance if connecting fails", func() {
+		instances = []*eureka.Instance{dead, live}
+
+		for n := 0; n < 4; n++ {
+			resp, err := client.Post("http://ORDERS/api/orders", "text/plain", strings.NewReader("payload"))
+			Expect(err).ToNot(HaveOccurred())
+
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal("/api/orders payload"))
+		}
+	})
+
+	It("fails once all instances have been tried", func() {
+		instances = []*eureka.Instance{dead}
+
+		_, err := client.Get("http://ORDERS/api/orders")
+		var opErr *net.OpError
+		Expect(errors.As(err, &opErr)).To(BeTrue())
+	})
+
+	It("fails if no instance is UP", func() {
+		instances = nil
+
+		_, err := client.Get("http://ORDERS/api/orders")
+		Expect(errors.Is(err, balancer.ErrNoInstances)).To(BeTrue())
+	})
+
+	It("uses the secure port for https URLs", func() {
+		var sent *url.URL
+		transport := balancer.NewTransport(
+			balancer.New(func(_ string) ([]*eureka.Instance, error) {
+				return []*eureka.Instance{live}, nil
+			}, balancer.RoundRobin()),
+			balancer.TransportBase(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				sent = r.URL
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+			})),
+		)
+
+		req, err := http.NewRequest("GET", "https://ORDERS/api/orders?id=1", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = transport.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sent.String()).To(Equal("https://" + net.JoinHostPort(live.IPAddr, strconv.Itoa(int(live.SecurePort))) + "/api/orders?id=1"))
+	})
+
+	Describe("on failure", func() {
+		var (
+			body      *trackingBody
+			req       *http.Request
+			transport *balancer.Transport
+		)
+
+		BeforeEach(func() {
+			transport = balancer.NewTransport(
+				balancer.New(func(_ string) ([]*eureka.Instance, error) {
+					return []*eureka.Instance{live, dead}, nil
+				}, balancer.RoundRobin()),
+				balancer.TransportBase(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					// fails without closing the body
+					return nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+				})),
+			)
+
+			body = &trackingBody{Reader: strings.NewReader("payload")}
+
+			var err error
+			req, err = http.NewRequest("POST", "http://ORDERS/api/orders", body)
+			Expect(err).ToNot(HaveOccurred())
+			req.GetBody = func() (io.ReadCloser, error) {
+				return &trackingBody{Reader: strings.NewReader("payload")}, nil
+			}
+		})
+
+		It("closes the request body once all instances have been tried", func() {
+			_, err := transport.RoundTrip(req)
+			Expect(err).To(HaveOccurred())
+			Expect(body.closed).To(BeTrue())
+		})
+
+		It("closes the request body if it cannot be replayed", func() {
+			req.GetBody = func() (io.ReadCloser, error) {
+				return nil, errors.New("some error")
+			}
+
+			_, err := transport.RoundTrip(req)
+			Expect(err).To(MatchError("some error"))
+			Expect(body.closed).To(BeTrue())
+		})
+	})
+})
+
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
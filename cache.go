@@ -0,0 +1,231 @@
+package eureka
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultRefreshInterval defines the default interval at which the cache
+// refreshes its copy of the registry.
+const DefaultRefreshInterval = 30 * time.Second
+
+// Cache keeps an in-memory copy of the registry that is refreshed in the
+// background, using delta queries whenever possible. Lookups are served from
+// memory. If the registry cannot be reached, the cache keeps serving the last
+// good copy and reports itself as stale.
+//
+// Apps and instances returned by the cache are shared and must not be
+// modified.
+type Cache struct {
+	source   refresher
+	interval time.Duration
+	logger   Logger
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mtx         sync.RWMutex
+	snapshot    *AppsResponse
+	apps        map[string]*App
+	instances   map[string]*Instance
+	vips        map[string][]*Instance
+	svips       map[string][]*Instance
+	lastRefresh time.Time
+	lastErr     error
+}
+
+type refresher interface {
+	RefreshContext(ctx context.Context, prev *AppsResponse) (*AppsResponse, error)
+}
+
+func newCache(source refresher, interval time.Duration, logger Logger) *Cache {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cache := &Cache{
+		source:   source,
+		interval: interval,
+		logger:   logger,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go cache.run(ctx)
+
+	return cache
+}
+
+// Stop the cache, i.e. the registry is no longer being refreshed. Lookups keep
+// being served from the last copy.
+func (c *Cache) Stop() {
+	c.cancel()
+	<-c.done
+}
+
+// Refresh synchronously brings the cache up to date. It can be used to make
+// sure the cache holds data before serving lookups.
+func (c *Cache) Refresh(ctx context.Context) error {
+	c.mtx.RLock()
+	prev := c.snapshot
+	c.mtx.RUnlock()
+
+	next, err := c.source.RefreshContext(ctx, prev)
+	if err != nil && ctx.Err() != nil {
+		// aborted by the caller, this says nothing about the registry
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.lastErr = err
+	if err != nil {
+		c.logger.Warn("Failed to refresh registry cache", "error", err, "lastRefresh", c.lastRefresh)
+		return err
+	}
+
+	c.index(next)
+	c.lastRefresh = time.Now()
+
+	return nil
+}
+
+// Apps returns all cached apps.
+func (c *Cache) Apps() []*App {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if c.snapshot == nil {
+		return nil
+	}
+
+	return append([]*App(nil), c.snapshot.Apps...)
+}
+
+// App returns the cached app with the given name. App names are matched
+// case-insensitively.
+func (c *Cache) App(appName string) (*App, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	app, found := c.apps[strings.ToUpper(appName)]
+	return app, found
+}
+
+// Instance returns the cached instance with the given ID.
+func (c *Cache) Instance(instanceID string) (*Instance, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	instance, found := c.instances[instanceID]
+	return instance, found
+}
+
+// VIP returns all cached instances registered for the given VIP address.
+func (c *Cache) VIP(vip string) []*Instance {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return append([]*Instance(nil), c.vips[strings.ToLower(vip)]...)
+}
+
+// SecureVIP returns all cached instances registered for the given secure VIP
+// address.
+func (c *Cache) SecureVIP(svip string) []*Instance {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return append([]*Instance(nil), c.svips[strings.ToLower(svip)]...)
+}
+
+// LastRefresh returns the time of the last successful refresh, or the zero
+// time if the cache has never been refreshed successfully.
+func (c *Cache) LastRefresh() time.Time {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.lastRefresh
+}
+
+// Age returns the time that has passed since the last successful refresh.
+func (c *Cache) Age() time.Duration {
+	return time.Since(c.LastRefresh())
+}
+
+// Stale reports whether the cache holds no data or the most recent refresh
+// failed, i.e. the cache is serving an outdated copy of the registry.
+func (c *Cache) Stale() bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.snapshot == nil || c.lastErr != nil
+}
+
+// Err returns the error of the most recent refresh, if any.
+func (c *Cache) Err() error {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.lastErr
+}
+
+func (c *Cache) run(ctx context.Context) {
+	defer close(c.done)
+
+	tick := time.NewTicker(c.interval)
+	defer tick.Stop()
+
+	c.Refresh(ctx)
+
+	for {
+		select {
+		case <-tick.C:
+			c.Refresh(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// index replaces the snapshot and rebuilds the lookup tables. Must be called
+// with the write lock held.
+func (c *Cache) index(snapshot *AppsResponse) {
+	c.snapshot = snapshot
+	c.apps = map[string]*App{}
+	c.instances = map[string]*Instance{}
+	c.vips = map[string][]*Instance{}
+	c.svips = map[string][]*Instance{}
+
+	for _, a := range snapshot.Apps {
+		c.apps[strings.ToUpper(a.Name)] = a
+
+		for _, i := range a.Instances {
+			c.instances[i.ID] = i
+
+			for _, vip := range splitVIPs(i.VIPAddr) {
+				c.vips[vip] = append(c.vips[vip], i)
+			}
+
+			for _, svip := range splitVIPs(i.SecureVIPAddr) {
+				c.svips[svip] = append(c.svips[svip], i)
+			}
+		}
+	}
+}
+
+// splitVIPs splits a comma-separated list of VIP addresses, the way Eureka
+// allows instances to register for multiple VIPs.
+func splitVIPs(addrs string) []string {
+	var vips []string
+	for _, vip := range strings.Split(addrs, ",") {
+		if vip = strings.ToLower(strings.TrimSpace(vip)); vip != "" {
+			vips = append(vips, vip)
+		}
+	}
+	return vips
+}
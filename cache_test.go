@@ -0,0 +1,169 @@
+package eureka
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		interval = 10 * time.Millisecond
+
+		one    *Instance
+		two    *Instance
+		source *mockRefresher
+		cache  *Cache
+	)
+
+	BeforeEach(func() {
+		one = &Instance{
+			ID:            "one",
+			AppName:       "EXISTING",
+			VIPAddr:       "existing, shared",
+			SecureVIPAddr: "secure-existing",
+		}
+
+		two = &Instance{
+			ID:      "two",
+			AppName: "OTHER",
+			VIPAddr: "shared",
+		}
+
+		source = &mockRefresher{
+			snapshot: &AppsResponse{
+				Apps: []*App{
+					{Name: "EXISTING", Instances: []*Instance{one}},
+					{Name: "OTHER", Instances: []*Instance{two}},
+				},
+			},
+		}
+
+		cache = newCache(source, interval, nopLogger{})
+
+		Eventually(cache.Stale).Should(BeFalse())
+	})
+
+	AfterEach(func() {
+		cache.Stop()
+	})
+
+	It("uses the default refresh interval unless the interval is positive", func() {
+		c := newCache(source, 0, nopLogger{})
+		defer c.Stop()
+
+		Expect(c.interval).To(Equal(DefaultRefreshInterval))
+		Eventually(c.Stale).Should(BeFalse())
+	})
+
+	It("serves apps", func() {
+		Expect(cache.Apps()).To(HaveLen(2))
+
+		app, found := cache.App("existing")
+		Expect(found).To(BeTrue())
+		Expect(app.Instances).To(ConsistOf(one))
+
+		_, found = cache.App("unknown")
+		Expect(found).To(BeFalse())
+	})
+
+	It("serves instances by ID", func() {
+		instance, found := cache.Instance("two")
+		Expect(found).To(BeTrue())
+		Expect(instance).To(Equal(two))
+	})
+
+	It("serves instances by VIP", func() {
+		Expect(cache.VIP("existing")).To(ConsistOf(one))
+		Expect(cache.VIP("SHARED")).To(ConsistOf(one, two))
+		Expect(cache.SecureVIP("secure-existing")).To(ConsistOf(one))
+		Expect(cache.VIP("unknown")).To(BeEmpty())
+	})
+
+	It("keeps refreshing", func() {
+		three := &Instance{ID: "three"}
+		source.Set(&AppsResponse{
+			Apps: []*App{{Name: "NEW", Instances: []*Instance{three}}},
+		})
+
+		Eventually(func() bool {
+			_, found := cache.Instance("three")
+			return found
+		}).Should(BeTrue())
+
+		Expect(cache.Age()).To(BeNumerically("<", time.Second))
+	})
+
+	It("passes the previous snapshot to the source", func() {
+		Eventually(source.LastPrev).ShouldNot(BeNil())
+	})
+
+	Context("when the registry cannot be reached", func() {
+		var someErr = errors.New("some error")
+
+		BeforeEach(func() {
+			source.Fail(someErr)
+		})
+
+		It("keeps serving the last good copy", func() {
+			Eventually(cache.Stale).Should(BeTrue())
+			Expect(cache.Err()).To(Equal(someErr))
+
+			_, found := cache.Instance("one")
+			Expect(found).To(BeTrue())
+		})
+
+		It("recovers once the registry is back", func() {
+			Eventually(cache.Stale).Should(BeTrue())
+
+			source.Fail(nil)
+
+			Eventually(cache.Stale).Should(BeFalse())
+			Expect(cache.Err()).ToNot(HaveOccurred())
+		})
+	})
+})
+
+type mockRefresher struct {
+	mtx      sync.Mutex
+	snapshot *AppsResponse
+	prev     *AppsResponse
+	err      error
+}
+
+func (m *mockRefresher) Set(snapshot *AppsResponse) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.snapshot = snapshot
+}
+
+func (m *mockRefresher) Fail(err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.err = err
+}
+
+func (m *mockRefresher) LastPrev() *AppsResponse {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.prev
+}
+
+func (m *mockRefresher) RefreshContext(_ context.Context, prev *AppsResponse) (*AppsResponse, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.prev = prev
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.snapshot, nil
+}
@@ -3,9 +3,9 @@ package eureka
 import (
 	"bytes"
 	"crypto/tls"
-	"encoding/xml"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -17,15 +17,26 @@ import (
 )
 
 type Client struct {
-	endpoints     []string
-	retrySelector retry.Selector
-	retryLimit    retry.Allow
-	retryDelay    retry.Delay
-	httpClient    *http.Client
-	timeout       time.Duration
-	transport     *http.Transport
-	oauth2Config  *clientcredentials.Config
-	tlsConfig     *tls.Config
+	endpoints        []string
+	retrySelector    retry.Selector
+	retryLimit       retry.Allow
+	retryDelay       retry.Delay
+	retryClassifier  retry.Classifier
+	breaker          *retry.Breaker
+	retryMaxElapsed  time.Duration
+	retryAfterLimit  time.Duration
+	observers        []Observer
+	logger           Logger
+	retryBudget      *retry.Budget
+	format           Format
+	httpClient       *http.Client
+	baseHTTPClient   *http.Client
+	timeout          time.Duration
+	transport        *http.Transport
+	transportOptions []func(*http.Transport)
+	roundTripper     http.RoundTripper
+	oauth2Config     *clientcredentials.Config
+	tlsConfig        *tls.Config
 }
 
 func NewClient(endpoints []string, options ...Option) *Client {
@@ -34,12 +45,15 @@ func NewClient(endpoints []string, options ...Option) *Client {
 	}
 
 	c := &Client{
-		endpoints:     endpoints,
-		timeout:       DefaultTimeout,
-		transport:     DefaultTransport,
-		retrySelector: DefaultRetrySelector,
-		retryLimit:    DefaultRetryLimit,
-		retryDelay:    DefaultRetryDelay,
+		endpoints:       endpoints,
+		timeout:         DefaultTimeout,
+		transport:       DefaultTransport,
+		retrySelector:   DefaultRetrySelector,
+		retryLimit:      DefaultRetryLimit,
+		retryDelay:      DefaultRetryDelay,
+		retryClassifier: DefaultRetryClassifier,
+		retryAfterLimit: DefaultRetryAfterLimit,
+		logger:          nopLogger{},
 	}
 
 	for _, opt := range options {
@@ -52,133 +66,387 @@ func NewClient(endpoints []string, options ...Option) *Client {
 }
 
 func (c *Client) newHTTPClient() *http.Client {
-	transport := c.transport
-	if c.tlsConfig != nil {
-		transport.TLSClientConfig = c.tlsConfig
-	}
-
-	httpClient := &http.Client{
-		Timeout:   c.timeout,
-		Transport: transport,
+	httpClient := c.baseHTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout:   c.timeout,
+			Transport: c.newTransport(),
+		}
 	}
 
 	if c.oauth2Config != nil {
 		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
-		httpClient = c.oauth2Config.Client(ctx)
+		wrapped := c.oauth2Config.Client(ctx)
+
+		// oauth2 only keeps the transport of the base client
+		wrapped.Timeout = httpClient.Timeout
+		wrapped.CheckRedirect = httpClient.CheckRedirect
+		wrapped.Jar = httpClient.Jar
+
+		httpClient = wrapped
 	}
 
 	return httpClient
 }
 
+// newTransport returns the round tripper of the internal HTTP client. Unless a
+// round tripper has been injected, it is a copy of the configured transport,
+// which keeps other clients using the same transport unaffected by this
+// client's options.
+func (c *Client) newTransport() http.RoundTripper {
+	if c.roundTripper != nil {
+		return c.roundTripper
+	}
+
+	transport := c.transport.Clone()
+	if c.tlsConfig != nil {
+		transport.TLSClientConfig = c.tlsConfig
+	}
+
+	for _, opt := range c.transportOptions {
+		opt(transport)
+	}
+
+	return transport
+}
+
 func (c *Client) Register(instance *Instance) error {
-	data, err := xml.Marshal(instance)
+	return c.RegisterContext(context.Background(), instance)
+}
+
+// RegisterContext is like Register but aborts the request and any pending
+// retries once ctx is done.
+func (c *Client) RegisterContext(ctx context.Context, instance *Instance) error {
+	data, err := c.format.marshal(instance)
 	if err != nil {
 		return err
 	}
 
-	return c.retry(c.do("POST", c.appPath(instance.AppName), data, http.StatusNoContent))
+	return c.retry(ctx, "Register", c.do(ctx, "POST", c.appPath(instance.AppName), data, http.StatusNoContent))
 }
 
 func (c *Client) Deregister(instance *Instance) error {
-	return c.retry(c.do("DELETE", c.appInstancePath(instance.AppName, instance.ID), nil, http.StatusOK))
+	return c.DeregisterContext(context.Background(), instance)
+}
+
+// DeregisterContext is like Deregister but aborts the request and any pending
+// retries once ctx is done.
+func (c *Client) DeregisterContext(ctx context.Context, instance *Instance) error {
+	return c.retry(ctx, "Deregister", c.do(ctx, "DELETE", c.appInstancePath(instance.AppName, instance.ID), nil, http.StatusOK))
 }
 
 func (c *Client) Heartbeat(instance *Instance) error {
-	return c.retry(c.do("PUT", c.appInstancePath(instance.AppName, instance.ID), nil, http.StatusOK))
+	return c.HeartbeatContext(context.Background(), instance)
+}
+
+// HeartbeatContext is like Heartbeat but aborts the request and any pending
+// retries once ctx is done.
+func (c *Client) HeartbeatContext(ctx context.Context, instance *Instance) error {
+	return c.retry(ctx, "Heartbeat", c.do(ctx, "PUT", c.appInstancePath(instance.AppName, instance.ID), nil, http.StatusOK))
+}
+
+// Registration registers the instance and keeps it registered until the
+// returned registration is stopped, see Registration for details.
+func (c *Client) Registration(instance *Instance, options ...RegistrationOption) *Registration {
+	return newRegistration(c, instance, c.logger, options...)
 }
 
 // Watch returns a new watcher that keeps polling the registry at the defined
-// interval and reports observed changes on its Events() channel.
-func (c *Client) Watch(pollInterval time.Duration) *Watcher {
-	return newWatcher(c, pollInterval)
+// interval and reports observed changes on its Events() channel. By default,
+// the watcher observes the whole registry, use WatchOptions to narrow it down.
+func (c *Client) Watch(pollInterval time.Duration, options ...WatchOption) *Watcher {
+	return newWatcher(c, pollInterval, options...)
+}
+
+// Cache returns a new cache that keeps a copy of the registry in memory and
+// refreshes it at the defined interval, DefaultRefreshInterval if it is not
+// positive.
+func (c *Client) Cache(refreshInterval time.Duration) *Cache {
+	return newCache(c, refreshInterval, c.logger)
 }
 
 func (c *Client) Apps() ([]*App, error) {
+	return c.AppsContext(context.Background())
+}
+
+// AppsContext is like Apps but aborts the request and any pending retries
+// once ctx is done.
+func (c *Client) AppsContext(ctx context.Context) ([]*App, error) {
 	result := new(AppsResponse)
-	if err := c.retry(c.get(c.appsPath(), result)); err != nil {
+	if err := c.retry(ctx, "Apps", c.get(ctx, c.appsPath(), result)); err != nil {
 		return nil, err
 	}
 
 	return result.Apps, nil
 }
 
+// Delta returns the changes to the registry since the last time the Eureka
+// server built its delta, including the apps hashcode after the changes.
+func (c *Client) Delta() (*AppsResponse, error) {
+	return c.DeltaContext(context.Background())
+}
+
+// DeltaContext is like Delta but aborts the request and any pending retries
+// once ctx is done.
+func (c *Client) DeltaContext(ctx context.Context) (*AppsResponse, error) {
+	result := new(AppsResponse)
+	if err := c.retry(ctx, "Delta", c.get(ctx, c.deltaPath(), result)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Refresh brings the registry snapshot prev up to date. It fetches the delta
+// and applies it to prev, falling back to a full fetch if prev is nil, the
+// delta cannot be fetched or the resulting hashcode does not match the one
+// reported by the server.
+func (c *Client) Refresh(prev *AppsResponse) (*AppsResponse, error) {
+	return c.RefreshContext(context.Background(), prev)
+}
+
+// RefreshContext is like Refresh but aborts the requests and any pending
+// retries once ctx is done.
+func (c *Client) RefreshContext(ctx context.Context, prev *AppsResponse) (*AppsResponse, error) {
+	if prev != nil {
+		delta, err := c.DeltaContext(ctx)
+		switch {
+		case err == nil:
+			if next, err := prev.ApplyDelta(delta); err == nil {
+				return next, nil
+			}
+		case ctx.Err() != nil:
+			return nil, err
+		}
+
+		// fall back to a full fetch, e.g. servers with deltas disabled respond
+		// to delta queries with 403
+	}
+
+	result := new(AppsResponse)
+	if err := c.retry(ctx, "Apps", c.get(ctx, c.appsPath(), result)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (c *Client) App(appName string) (*App, error) {
+	return c.AppContext(context.Background(), appName)
+}
+
+// AppContext is like App but aborts the request and any pending retries
+// once ctx is done.
+func (c *Client) AppContext(ctx context.Context, appName string) (*App, error) {
 	app := new(App)
-	err := c.retry(c.get(c.appPath(appName), app))
+	err := c.retry(ctx, "App", c.get(ctx, c.appPath(appName), app))
 	return app, err
 }
 
 func (c *Client) AppInstance(appName, instanceID string) (*Instance, error) {
+	return c.AppInstanceContext(context.Background(), appName, instanceID)
+}
+
+// AppInstanceContext is like AppInstance but aborts the request and any
+// pending retries once ctx is done.
+func (c *Client) AppInstanceContext(ctx context.Context, appName, instanceID string) (*Instance, error) {
 	instance := new(Instance)
-	err := c.retry(c.get(c.appInstancePath(appName, instanceID), instance))
+	err := c.retry(ctx, "AppInstance", c.get(ctx, c.appInstancePath(appName, instanceID), instance))
 	return instance, err
 }
 
 func (c *Client) Instance(instanceID string) (*Instance, error) {
+	return c.InstanceContext(context.Background(), instanceID)
+}
+
+// InstanceContext is like Instance but aborts the request and any pending
+// retries once ctx is done.
+func (c *Client) InstanceContext(ctx context.Context, instanceID string) (*Instance, error) {
 	instance := new(Instance)
-	err := c.retry(c.get(c.instancePath(instanceID), instance))
+	err := c.retry(ctx, "Instance", c.get(ctx, c.instancePath(instanceID), instance))
 	return instance, err
 }
 
+// UpdateMetadata adds or replaces the given metadata entries of a registered
+// instance without having to re-register it.
+func (c *Client) UpdateMetadata(instance *Instance, metadata Metadata) error {
+	return c.UpdateMetadataContext(context.Background(), instance, metadata)
+}
+
+// UpdateMetadataContext is like UpdateMetadata but aborts the request and any
+// pending retries once ctx is done.
+func (c *Client) UpdateMetadataContext(ctx context.Context, instance *Instance, metadata Metadata) error {
+	return c.retry(ctx, "UpdateMetadata", c.do(ctx, "PUT", c.appInstanceMetadataPath(instance.AppName, instance.ID, metadata), nil, http.StatusOK))
+}
+
+// VIP returns all instances registered for the given VIP address.
+func (c *Client) VIP(vip string) ([]*Instance, error) {
+	return c.VIPContext(context.Background(), vip)
+}
+
+// VIPContext is like VIP but aborts the request and any pending retries once
+// ctx is done.
+func (c *Client) VIPContext(ctx context.Context, vip string) ([]*Instance, error) {
+	return c.vipInstances(ctx, "VIP", c.vipPath(vip))
+}
+
+// SecureVIP returns all instances registered for the given secure VIP address.
+func (c *Client) SecureVIP(svip string) ([]*Instance, error) {
+	return c.SecureVIPContext(context.Background(), svip)
+}
+
+// SecureVIPContext is like SecureVIP but aborts the request and any pending
+// retries once ctx is done.
+func (c *Client) SecureVIPContext(ctx context.Context, svip string) ([]*Instance, error) {
+	return c.vipInstances(ctx, "SecureVIP", c.secureVIPPath(svip))
+}
+
+func (c *Client) vipInstances(ctx context.Context, operation, path string) ([]*Instance, error) {
+	result := new(AppsResponse)
+	if err := c.retry(ctx, operation, c.get(ctx, path, result)); err != nil {
+		return nil, err
+	}
+
+	var instances []*Instance
+	for _, a := range result.Apps {
+		instances = append(instances, a.Instances...)
+	}
+
+	return instances, nil
+}
+
 func (c *Client) StatusOverride(instance *Instance, status Status) error {
-	return c.retry(c.do("PUT", c.appInstanceStatusPath(instance.AppName, instance.ID, status), nil, http.StatusOK))
+	return c.StatusOverrideContext(context.Background(), instance, status)
+}
+
+// StatusOverrideContext is like StatusOverride but aborts the request and any
+// pending retries once ctx is done.
+func (c *Client) StatusOverrideContext(ctx context.Context, instance *Instance, status Status) error {
+	return c.retry(ctx, "StatusOverride", c.do(ctx, "PUT", c.appInstanceStatusPath(instance.AppName, instance.ID, status), nil, http.StatusOK))
 }
 
 func (c *Client) RemoveStatusOverride(instance *Instance, fallback Status) error {
-	return c.retry(c.do("DELETE", c.appInstanceStatusPath(instance.AppName, instance.ID, fallback), nil, http.StatusOK))
+	return c.RemoveStatusOverrideContext(context.Background(), instance, fallback)
+}
+
+// RemoveStatusOverrideContext is like RemoveStatusOverride but aborts the
+// request and any pending retries once ctx is done.
+func (c *Client) RemoveStatusOverrideContext(ctx context.Context, instance *Instance, fallback Status) error {
+	return c.retry(ctx, "RemoveStatusOverride", c.do(ctx, "DELETE", c.appInstanceStatusPath(instance.AppName, instance.ID, fallback), nil, http.StatusOK))
 }
 
-func (c *Client) retry(action retry.Action) error {
-	return retry.NewStrategy(c.retrySelector(c.endpoints), c.retryLimit, c.retryDelay).Apply(action)
+// request sends a single attempt of a request to the given endpoint and
+// returns the status code of the response, if any.
+type request func(endpoint string) (int, error)
+
+func (c *Client) retry(ctx context.Context, operation string, req request) error {
+	var status int
+	action := func(endpoint string) error {
+		var err error
+		status, err = req(endpoint)
+		return err
+	}
+
+	selector := c.retrySelector
+	options := []retry.Option{retry.Context(ctx), retry.Classify(c.retryClassifier)}
+
+	observers := c.observers
+	if _, silent := c.logger.(nopLogger); !silent {
+		observers = append(observers[:len(observers):len(observers)], logObserver{c.logger})
+	}
+
+	for _, o := range observers {
+		observer := o
+		options = append(options, retry.Observe(retry.ObserverFunc(func(ctx context.Context, a retry.Attempt) {
+			observer.ObserveAttempt(ctx, Attempt{
+				Operation:  operation,
+				Endpoint:   a.Endpoint,
+				Number:     a.Number,
+				Delay:      a.Delay,
+				Duration:   a.Duration,
+				StatusCode: status,
+				Err:        a.Err,
+			})
+		})))
+	}
+
+	if c.breaker != nil {
+		selector = c.breaker.Selector(selector)
+		options = append(options, retry.Track(c.breaker))
+	}
+
+	if c.retryAfterLimit > 0 {
+		options = append(options, retry.Pace(retry.RetryAfter(c.retryDelay, c.retryAfterLimit)))
+	}
+
+	if c.retryMaxElapsed > 0 {
+		options = append(options, retry.MaxElapsed(c.retryMaxElapsed))
+	}
+
+	if c.retryBudget != nil {
+		options = append(options, retry.Spend(c.retryBudget))
+	}
+
+	strategy := retry.NewStrategy(
+		selector(c.endpoints),
+		c.retryLimit,
+		c.retryDelay,
+		options...,
+	)
+
+	return strategy.Apply(action)
 }
 
-func (c *Client) do(method, path string, body []byte, respCode int) retry.Action {
-	return func(endpoint string) error {
+func (c *Client) do(ctx context.Context, method, path string, body []byte, respCode int) request {
+	return func(endpoint string) (int, error) {
 		req, err := http.NewRequest(method, fmt.Sprintf("%s/%s", endpoint, path), bytes.NewBuffer(body))
 		if err != nil {
-			return err
+			return 0, err
 		}
+		req = req.WithContext(ctx)
 
-		req.Header.Add("Content-Type", "application/xml")
-		req.Header.Add("Accept", "application/xml")
+		req.Header.Add("Content-Type", c.format.contentType())
+		req.Header.Add("Accept", c.format.contentType())
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			return err
+			return 0, err
 		}
+		defer resp.Body.Close()
 
 		if resp.StatusCode != respCode {
-			return fmt.Errorf("Unexpected response code %d", resp.StatusCode)
+			return resp.StatusCode, newHTTPError(req, endpoint, resp)
 		}
 
-		return nil
+		return resp.StatusCode, nil
 	}
 }
 
-func (c *Client) get(path string, result interface{}) retry.Action {
-	return func(endpoint string) error {
+func (c *Client) get(ctx context.Context, path string, result interface{}) request {
+	return func(endpoint string) (int, error) {
 		req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s", endpoint, path), nil)
 		if err != nil {
-			return err
+			return 0, err
 		}
+		req = req.WithContext(ctx)
 
-		req.Header.Add("Accept", "application/xml")
+		req.Header.Add("Accept", c.format.contentType())
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			return err
+			return 0, err
 		}
+		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("Unexpected response code %d", resp.StatusCode)
+			return resp.StatusCode, newHTTPError(req, endpoint, resp)
 		}
 
-		defer resp.Body.Close()
-		if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
-			return err
+		if err := c.format.decode(resp.Body, result); err != nil {
+			return resp.StatusCode, err
 		}
 
-		return nil
+		return resp.StatusCode, nil
 	}
 }
 
@@ -186,6 +454,10 @@ func (c *Client) appsPath() string {
 	return "apps"
 }
 
+func (c *Client) deltaPath() string {
+	return fmt.Sprintf("%s/delta", c.appsPath())
+}
+
 func (c *Client) appPath(appName string) string {
 	return fmt.Sprintf("%s/%s", c.appsPath(), appName)
 }
@@ -198,6 +470,22 @@ func (c *Client) instancePath(instanceID string) string {
 	return fmt.Sprintf("instances/%s", instanceID)
 }
 
+func (c *Client) appInstanceMetadataPath(appName, instanceID string, metadata Metadata) string {
+	query := url.Values{}
+	for k, v := range metadata {
+		query.Set(k, v)
+	}
+	return fmt.Sprintf("%s/metadata?%s", c.appInstancePath(appName, instanceID), query.Encode())
+}
+
+func (c *Client) vipPath(vip string) string {
+	return fmt.Sprintf("vips/%s", vip)
+}
+
+func (c *Client) secureVIPPath(svip string) string {
+	return fmt.Sprintf("svips/%s", svip)
+}
+
 func (c *Client) appInstanceStatusPath(appName, instanceID string, status Status) string {
 	return fmt.Sprintf("%s/status?value=%s", c.appInstancePath(appName, instanceID), status)
 }
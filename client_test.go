@@ -1,16 +1,20 @@
 package eureka_test
 
 import (
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
+	"golang.org/x/net/context"
 
 	"github.com/virajago/go-scs-eureka"
 	"github.com/virajago/go-scs-eureka/retry"
@@ -84,7 +88,7 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				err := client.Register(instance)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
@@ -125,7 +129,7 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				err := client.Deregister(instance)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
@@ -166,11 +170,374 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				err := client.Heartbeat(instance)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
 
+	Describe("JSON wire format", func() {
+		BeforeEach(func() {
+			client = eureka.NewClient(
+				[]string{server.URL()},
+				eureka.RetryLimit(retry.MaxRetries(numRetries)),
+				eureka.RetryDelay(retry.NoDelay()),
+				eureka.WireFormat(eureka.FormatJSON),
+			)
+		})
+
+		It("sends the instance wrapped in an instance object", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", fmt.Sprintf("/apps/%s", instance.AppName)),
+					ghttp.VerifyContentType("application/json"),
+					ghttp.VerifyHeaderKV("Accept", "application/json"),
+					func(w http.ResponseWriter, r *http.Request) {
+						body := map[string]*eureka.Instance{}
+						Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+						Expect(body).To(HaveKey("instance"))
+						Expect(body["instance"].Equals(instance)).To(BeTrue())
+					},
+					ghttp.RespondWith(http.StatusNoContent, nil),
+				),
+			)
+
+			Expect(client.Register(instance)).To(Succeed())
+		})
+
+		It("decodes the registered apps", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/apps"),
+					ghttp.VerifyHeaderKV("Accept", "application/json"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"applications": {
+							"versions__delta": "1",
+							"apps__hashcode": "UP_1_",
+							"application": [{
+								"name": "MYAPP",
+								"instance": [{
+									"instanceId": "id",
+									"app": "MYAPP",
+									"status": "UP",
+									"port": {"$": 8080, "@enabled": "true"},
+									"dataCenterInfo": {
+										"@class": "com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo",
+										"name": "MyOwn"
+									},
+									"metadata": {"@class": "java.util.Collections$EmptyMap"}
+								}]
+							}]
+						}
+					}`),
+				),
+			)
+
+			apps, err := client.Apps()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(apps).To(HaveLen(1))
+			Expect(apps[0].Name).To(Equal("MYAPP"))
+			Expect(apps[0].Instances).To(HaveLen(1))
+			Expect(apps[0].Instances[0].ID).To(Equal("id"))
+			Expect(apps[0].Instances[0].Port).To(Equal(eureka.Port(8080)))
+			Expect(apps[0].Instances[0].Metadata).To(BeEmpty())
+		})
+	})
+
+	Describe("errors", func() {
+		var body string
+
+		BeforeEach(func() {
+			body = ""
+			route := fmt.Sprintf("/apps/%s/%s", instance.AppName, instance.ID)
+			for i := 0; i < numRetries; i++ {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("PUT", route),
+						ghttp.RespondWithPtr(&statusCode, &body),
+					),
+				)
+			}
+		})
+
+		Context("when the instance is unknown", func() {
+			BeforeEach(func() {
+				statusCode = http.StatusNotFound
+				body = "Instance not found."
+			})
+
+			It("does not retry the request", func() {
+				client.Heartbeat(instance)
+				Expect(server.ReceivedRequests()).To(HaveLen(1))
+			})
+
+			It("returns an error matching ErrNotFound", func() {
+				err := client.Heartbeat(instance)
+				Expect(errors.Is(err, eureka.ErrNotFound)).To(BeTrue())
+				Expect(errors.Is(err, eureka.ErrConflict)).To(BeFalse())
+			})
+
+			It("returns an HTTPError describing the failed request", func() {
+				err := client.Heartbeat(instance)
+
+				var httpErr *eureka.HTTPError
+				Expect(errors.As(err, &httpErr)).To(BeTrue())
+				Expect(httpErr.Method).To(Equal("PUT"))
+				Expect(httpErr.Endpoint).To(Equal(server.URL()))
+				Expect(httpErr.URL).To(Equal(fmt.Sprintf("%s/apps/%s/%s", server.URL(), instance.AppName, instance.ID)))
+				Expect(httpErr.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(httpErr.Body).To(Equal("Instance not found."))
+			})
+
+			It("masks passwords in the HTTPError", func() {
+				endpoint := strings.Replace(server.URL(), "://", "://user:s3cr3t@", 1)
+				client = eureka.NewClient([]string{endpoint}, eureka.RetryLimit(retry.NoRetries()))
+
+				err := client.Heartbeat(instance)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).ToNot(ContainSubstring("s3cr3t"))
+
+				var httpErr *eureka.HTTPError
+				Expect(errors.As(err, &httpErr)).To(BeTrue())
+				Expect(httpErr.Endpoint).To(Equal(strings.Replace(server.URL(), "://", "://user:xxxxx@", 1)))
+				Expect(httpErr.URL).ToNot(ContainSubstring("s3cr3t"))
+			})
+		})
+
+		Context("when the server reports a conflict", func() {
+			BeforeEach(func() {
+				statusCode = http.StatusConflict
+			})
+
+			It("returns an error matching ErrConflict", func() {
+				err := client.Heartbeat(instance)
+				Expect(errors.Is(err, eureka.ErrConflict)).To(BeTrue())
+			})
+		})
+
+		It("lists the failure of each attempt", func() {
+			statusCode = http.StatusInternalServerError
+
+			err := client.Heartbeat(instance)
+
+			var retryErr *retry.Error
+			Expect(errors.As(err, &retryErr)).To(BeTrue())
+			Expect(retryErr.Attempts).To(HaveLen(numRetries))
+			for i, a := range retryErr.Attempts {
+				Expect(a.Attempt).To(Equal(uint(i)))
+				Expect(a.Endpoint).To(Equal(server.URL()))
+			}
+		})
+	})
+
+	Describe("circuit breaking", func() {
+		var (
+			failing *ghttp.Server
+			breaker *retry.Breaker
+		)
+
+		BeforeEach(func() {
+			failing = ghttp.NewServer()
+			failing.AllowUnhandledRequests = true
+			failing.UnhandledRequestStatusCode = http.StatusServiceUnavailable
+
+			server.AllowUnhandledRequests = true
+			server.UnhandledRequestStatusCode = http.StatusOK
+
+			breaker = retry.NewBreaker(1, time.Minute)
+			client = eureka.NewClient(
+				[]string{failing.URL(), server.URL()},
+				eureka.RetryLimit(retry.MaxRetries(numRetries)),
+				eureka.RetryDelay(retry.NoDelay()),
+				eureka.CircuitBreaker(breaker),
+			)
+		})
+
+		AfterEach(func() {
+			failing.Close()
+		})
+
+		It("skips quarantined endpoints", func() {
+			for n := 0; n < 3; n++ {
+				Expect(client.Heartbeat(instance)).To(Succeed())
+			}
+
+			Expect(failing.ReceivedRequests()).To(HaveLen(1))
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+		})
+
+		It("reports endpoint health", func() {
+			client.Heartbeat(instance)
+
+			states := map[string]retry.BreakerState{}
+			for _, h := range breaker.Health() {
+				states[h.Endpoint] = h.State
+			}
+
+			Expect(states).To(Equal(map[string]retry.BreakerState{
+				failing.URL(): retry.BreakerOpen,
+				server.URL():  retry.BreakerClosed,
+			}))
+		})
+	})
+
+	Describe("throttling", func() {
+		BeforeEach(func() {
+			route := fmt.Sprintf("/apps/%s/%s", instance.AppName, instance.ID)
+			header := http.Header{"Retry-After": []string{"1"}}
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", route),
+					ghttp.RespondWith(http.StatusServiceUnavailable, "Rate limited", header),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", route),
+					ghttp.RespondWith(http.StatusTooManyRequests, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", route),
+					ghttp.RespondWith(http.StatusServiceUnavailable, nil, header),
+				),
+			)
+
+			client = eureka.NewClient(
+				[]string{server.URL()},
+				eureka.RetryLimit(retry.MaxRetries(numRetries)),
+				eureka.RetryDelay(retry.NoDelay()),
+				eureka.RetryAfterLimit(100*time.Millisecond),
+			)
+		})
+
+		It("honors the Retry-After header up to the limit", func() {
+			start := time.Now()
+			client.Heartbeat(instance)
+
+			Expect(server.ReceivedRequests()).To(HaveLen(numRetries))
+			Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		It("returns an error matching ErrThrottled", func() {
+			err := client.Heartbeat(instance)
+			Expect(errors.Is(err, eureka.ErrThrottled)).To(BeTrue())
+
+			var httpErr *eureka.HTTPError
+			Expect(errors.As(err, &httpErr)).To(BeTrue())
+			Expect(httpErr.RetryAfter).To(Equal(time.Second))
+			Expect(httpErr.Error()).To(ContainSubstring("throttled, retry after 1s"))
+		})
+	})
+
+	Describe("observers", func() {
+		var attempts []eureka.Attempt
+
+		BeforeEach(func() {
+			attempts = nil
+			route := fmt.Sprintf("/apps/%s/%s", instance.AppName, instance.ID)
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", route),
+					ghttp.RespondWith(http.StatusInternalServerError, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", route),
+					ghttp.RespondWith(http.StatusOK, nil),
+				),
+			)
+
+			client = eureka.NewClient(
+				[]string{server.URL()},
+				eureka.RetryLimit(retry.MaxRetries(numRetries)),
+				eureka.RetryDelay(retry.NoDelay()),
+				eureka.AttemptObserver(eureka.ObserverFunc(func(_ context.Context, a eureka.Attempt) {
+					attempts = append(attempts, a)
+				})),
+			)
+		})
+
+		It("are notified about every attempt", func() {
+			Expect(client.Heartbeat(instance)).To(Succeed())
+
+			Expect(attempts).To(HaveLen(2))
+			for i, a := range attempts {
+				Expect(a.Operation).To(Equal("Heartbeat"))
+				Expect(a.Endpoint).To(Equal(server.URL()))
+				Expect(a.Number).To(Equal(uint(i)))
+				Expect(a.Duration).To(BeNumerically(">", 0))
+			}
+
+			Expect(attempts[0].StatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(attempts[0].Err).To(HaveOccurred())
+			Expect(attempts[1].StatusCode).To(Equal(http.StatusOK))
+			Expect(attempts[1].Err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("retry budget", func() {
+		BeforeEach(func() {
+			server.AllowUnhandledRequests = true
+			server.UnhandledRequestStatusCode = http.StatusInternalServerError
+
+			client = eureka.NewClient(
+				[]string{server.URL()},
+				eureka.RetryLimit(retry.MaxRetries(numRetries)),
+				eureka.RetryDelay(retry.NoDelay()),
+				eureka.RetryBudget(retry.NewBudget(0, 2)),
+			)
+		})
+
+		It("limits retries across all requests of the client", func() {
+			err := client.Heartbeat(instance)
+			Expect(err).To(HaveOccurred())
+			Expect(server.ReceivedRequests()).To(HaveLen(numRetries))
+
+			err = client.Heartbeat(instance)
+			Expect(errors.Is(err, retry.ErrBudgetExhausted)).To(BeTrue())
+			Expect(server.ReceivedRequests()).To(HaveLen(numRetries + 1))
+		})
+	})
+
+	Describe(".HeartbeatContext", func() {
+		var unblock chan struct{}
+
+		BeforeEach(func() {
+			unblock = make(chan struct{})
+			route := fmt.Sprintf("/apps/%s/%s", instance.AppName, instance.ID)
+			for i := 0; i < numRetries; i++ {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("PUT", route),
+						func(w http.ResponseWriter, r *http.Request) {
+							<-unblock
+						},
+					),
+				)
+			}
+		})
+
+		AfterEach(func() {
+			close(unblock)
+		})
+
+		It("sends no request if the context is already done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := client.HeartbeatContext(ctx, instance)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+
+		It("aborts the in-flight request once the context is done", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := client.HeartbeatContext(ctx, instance)
+			Expect(err).To(HaveOccurred())
+			Expect(ctx.Err()).To(Equal(context.DeadlineExceeded))
+			Expect(len(server.ReceivedRequests())).To(BeNumerically("<", numRetries))
+		})
+	})
+
 	Describe(".Apps", func() {
 		var app *eureka.App
 
@@ -227,11 +594,143 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				_, err := client.Apps()
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
 
+	Describe(".Delta", func() {
+		var delta eureka.AppsResponse
+
+		BeforeEach(func() {
+			app, err := appFixture()
+			Expect(err).ToNot(HaveOccurred())
+			app.Instances[0].ActionType = eureka.ActionTypeAdded
+
+			delta = eureka.AppsResponse{
+				VersionDelta: 3,
+				Hashcode:     "UP_1_",
+				Apps:         []*eureka.App{app},
+			}
+
+			body, err := xml.Marshal(delta)
+			Expect(err).ToNot(HaveOccurred())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/apps/delta"),
+					ghttp.RespondWith(http.StatusOK, body),
+				),
+			)
+		})
+
+		It("returns the delta", func() {
+			actual, err := client.Delta()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual.VersionDelta).To(Equal(3))
+			Expect(actual.Hashcode).To(Equal("UP_1_"))
+			Expect(actual.Apps).To(HaveLen(1))
+			Expect(actual.Apps[0].Instances[0].ActionType).To(Equal(eureka.ActionTypeAdded))
+		})
+	})
+
+	Describe(".Refresh", func() {
+		var (
+			app   *eureka.App
+			delta eureka.AppsResponse
+			full  eureka.AppsResponse
+		)
+
+		BeforeEach(func() {
+			var err error
+			app, err = appFixture()
+			Expect(err).ToNot(HaveOccurred())
+
+			added := *app.Instances[0]
+			added.ActionType = eureka.ActionTypeAdded
+
+			delta = eureka.AppsResponse{
+				VersionDelta: 2,
+				Hashcode:     "UP_1_",
+				Apps:         []*eureka.App{{Name: app.Name, Instances: []*eureka.Instance{&added}}},
+			}
+
+			full = eureka.AppsResponse{
+				VersionDelta: 2,
+				Hashcode:     "UP_1_",
+				Apps:         []*eureka.App{app},
+			}
+		})
+
+		respondWith := func(path string, response eureka.AppsResponse) {
+			body, err := xml.Marshal(response)
+			Expect(err).ToNot(HaveOccurred())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", path),
+					ghttp.RespondWith(http.StatusOK, body),
+				),
+			)
+		}
+
+		It("fetches the full registry without a previous snapshot", func() {
+			respondWith("/apps", full)
+
+			actual, err := client.Refresh(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual.Apps).To(HaveLen(1))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("applies the delta to the previous snapshot", func() {
+			respondWith("/apps/delta", delta)
+
+			actual, err := client.Refresh(&eureka.AppsResponse{VersionDelta: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual.VersionDelta).To(Equal(2))
+			Expect(actual.Apps).To(HaveLen(1))
+			Expect(actual.Apps[0].Instances[0].ID).To(Equal(app.Instances[0].ID))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("falls back to a full fetch if the hashcode does not match", func() {
+			delta.Hashcode = "UP_2_"
+			respondWith("/apps/delta", delta)
+			respondWith("/apps", full)
+
+			actual, err := client.Refresh(&eureka.AppsResponse{VersionDelta: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual.Apps).To(HaveLen(1))
+			Expect(actual.Apps[0].Instances[0].ActionType).To(Equal(eureka.ActionTypeNone))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("falls back to a full fetch if the delta cannot be fetched", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/apps/delta"),
+					ghttp.RespondWith(http.StatusForbidden, nil),
+				),
+			)
+			respondWith("/apps", full)
+
+			actual, err := client.Refresh(&eureka.AppsResponse{VersionDelta: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual.Apps).To(HaveLen(1))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("does not fall back to a full fetch once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := client.RefreshContext(ctx, &eureka.AppsResponse{VersionDelta: 1})
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+
 	Describe(".App", func() {
 		var app *eureka.App
 
@@ -283,7 +782,7 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				_, err := client.App(app.Name)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
@@ -337,7 +836,7 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				_, err := client.AppInstance(instance.AppName, instance.ID)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
@@ -391,7 +890,7 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				_, err := client.Instance(instance.ID)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
@@ -437,6 +936,134 @@ var _ = Describe("client", func() {
 				return len(server.ReceivedRequests())
 			}).Should(BeNumerically(">", 10))
 		})
+
+		It("queries only the apps it is scoped to", func() {
+			body, err := xml.Marshal(app)
+			Expect(err).ToNot(HaveOccurred())
+
+			server.Reset()
+			server.RouteToHandler("GET", "/apps/"+app.Name, ghttp.RespondWith(http.StatusOK, body))
+
+			watcher := client.Watch(10*time.Millisecond, eureka.WatchApps(app.Name))
+			defer watcher.Stop()
+
+			expectedEvent := eureka.Event{eureka.EventInstanceRegistered, app.Instances[0]}
+			Eventually(watcher.Events()).Should(Receive(Equal(expectedEvent)))
+		})
+	})
+
+	Describe(".Cache", func() {
+		It("keeps refreshing if the server has deltas disabled", func() {
+			app, err := appFixture()
+			Expect(err).ToNot(HaveOccurred())
+
+			body, err := xml.Marshal(eureka.AppsResponse{Apps: []*eureka.App{app}})
+			Expect(err).ToNot(HaveOccurred())
+
+			server.RouteToHandler("GET", "/apps", ghttp.RespondWith(http.StatusOK, body))
+			server.RouteToHandler("GET", "/apps/delta", ghttp.RespondWith(http.StatusForbidden, nil))
+
+			cache := client.Cache(10 * time.Millisecond)
+			defer cache.Stop()
+
+			fullFetches := func() int {
+				count := 0
+				for _, r := range server.ReceivedRequests() {
+					if r.URL.Path == "/apps" {
+						count++
+					}
+				}
+				return count
+			}
+
+			Eventually(fullFetches).Should(BeNumerically(">", 2))
+			Expect(cache.Stale()).To(BeFalse())
+			Expect(cache.Err()).ToNot(HaveOccurred())
+			Expect(cache.Apps()).To(HaveLen(1))
+		})
+	})
+
+	Describe(".UpdateMetadata", func() {
+		var metadata = eureka.Metadata{"weight": "10", "version": "1.2"}
+
+		BeforeEach(func() {
+			route := fmt.Sprintf("/apps/%s/%s/metadata", instance.AppName, instance.ID)
+			statusCode = http.StatusOK
+			for i := 0; i < numRetries; i++ {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("PUT", route, "version=1.2&weight=10"),
+						ghttp.RespondWithPtr(&statusCode, nil),
+					),
+				)
+			}
+		})
+
+		It("sends the correct request", func() {
+			client.UpdateMetadata(instance, metadata)
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("returns no error", func() {
+			err := client.UpdateMetadata(instance, metadata)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				statusCode = http.StatusInternalServerError
+			})
+
+			It("retries the request", func() {
+				client.UpdateMetadata(instance, metadata)
+				Expect(server.ReceivedRequests()).To(HaveLen(numRetries))
+			})
+
+			It("returns an error", func() {
+				err := client.UpdateMetadata(instance, metadata)
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
+			})
+		})
+	})
+
+	Describe(".VIP", func() {
+		var app *eureka.App
+
+		BeforeEach(func() {
+			var err error
+			app, err = appFixture()
+			Expect(err).ToNot(HaveOccurred())
+
+			body, err := xml.Marshal(eureka.AppsResponse{Apps: []*eureka.App{app, app}})
+			Expect(err).ToNot(HaveOccurred())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", fmt.Sprintf("/vips/%s", instance.VIPAddr)),
+					ghttp.RespondWith(http.StatusOK, body),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", fmt.Sprintf("/svips/%s", instance.SecureVIPAddr)),
+					ghttp.RespondWith(http.StatusOK, body),
+				),
+			)
+		})
+
+		It("returns the instances of all matching apps", func() {
+			instances, err := client.VIP(instance.VIPAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(Equal([]*eureka.Instance{app.Instances[0], app.Instances[0]}))
+		})
+
+		It("queries secure VIPs", func() {
+			_, err := client.VIP(instance.VIPAddr)
+			Expect(err).ToNot(HaveOccurred())
+
+			instances, err := client.SecureVIP(instance.SecureVIPAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(HaveLen(2))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
 	})
 
 	Describe(".StatusOverride", func() {
@@ -477,7 +1104,7 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				err := client.StatusOverride(instance, status)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
@@ -520,7 +1147,7 @@ var _ = Describe("client", func() {
 
 			It("returns an error", func() {
 				err := client.RemoveStatusOverride(instance, fallback)
-				Expect(err).To(MatchError("Unexpected response code 500"))
+				Expect(err).To(MatchError(ContainSubstring("Unexpected response code 500")))
 			})
 		})
 	})
@@ -24,6 +24,7 @@ func main() {
 		instancesCmd,
 		overrideCmd,
 		removeOverrideCmd,
+		metadataCmd,
 	}
 
 	app.Run(os.Args)
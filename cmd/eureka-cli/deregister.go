@@ -5,7 +5,7 @@ import (
 
 	"github.com/codegangsta/cli"
 
-	"github.com/st3v/go-eureka"
+	"github.com/virajago/go-scs-eureka"
 )
 
 var deregisterCmd = cli.Command{
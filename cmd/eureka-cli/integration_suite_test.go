@@ -17,7 +17,7 @@ import (
 	"github.com/onsi/gomega/gexec"
 	"github.com/pborman/uuid"
 
-	"github.com/st3v/go-eureka"
+	"github.com/virajago/go-scs-eureka"
 )
 
 func TestCLI(t *testing.T) {
@@ -30,7 +30,7 @@ var binPath string
 
 var _ = BeforeSuite(func() {
 	var err error
-	binPath, err = gexec.Build("github.com/st3v/go-eureka/cmd/eureka-cli")
+	binPath, err = gexec.Build("github.com/virajago/go-scs-eureka/cmd/eureka-cli")
 	Expect(err).ToNot(HaveOccurred())
 })
 
@@ -11,7 +11,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
 
-	"github.com/st3v/go-eureka"
+	"github.com/virajago/go-scs-eureka"
 )
 
 // requires a running Eureka instance, e.g. `docker run -p 8080:8080 netflixoss/eureka:1.3.1`
@@ -131,6 +131,22 @@ var _ = Describe("CLI", func() {
 		Expect(result.Instances[0].Status).To(Equal(eureka.StatusUp))
 		Expect(result.Instances[0].StatusOverride).To(Equal(eureka.StatusUnknown))
 
+		// update metadata
+		session = execBin(append([]string{"metadata", "set", "weight=10", "-i", instanceFilePaths[0]}, endpointFlags()...)...)
+		Eventually(session).Should(gexec.Exit(0))
+
+		// verify metadata update
+		session = execBin(append([]string{"instances", "-i", instances[0].ID}, endpointFlags()...)...)
+		Eventually(session).Should(gexec.Exit(0))
+
+		result = new(instancesResult)
+		err = xml.Unmarshal(session.Out.Contents(), result)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Instances).To(HaveLen(1))
+		Expect(result.Instances[0].Metadata).To(HaveKeyWithValue("weight", "10"))
+		Expect(result.Instances[0].Metadata).To(HaveKeyWithValue("key", "value"))
+
 		// deregister
 		for _, path := range instanceFilePaths {
 			session = execBin(append([]string{"deregister", "-i", path}, endpointFlags()...)...)
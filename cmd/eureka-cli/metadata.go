@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/virajago/go-scs-eureka"
+)
+
+var getMetadata = func(c *cli.Context) eureka.Metadata {
+	if !c.Args().Present() {
+		fmt.Fprintln(c.App.Writer, "must specify at least one key=value pair")
+		os.Exit(1)
+	}
+
+	metadata := eureka.Metadata{}
+	for _, arg := range c.Args() {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			fmt.Fprintf(c.App.Writer, "invalid metadata entry '%s', must be key=value\n", arg)
+			os.Exit(1)
+		}
+		metadata[kv[0]] = kv[1]
+	}
+
+	return metadata
+}
+
+var metadataCmd = cli.Command{
+	Name:  "metadata",
+	Usage: "manage the metadata of a registered instance",
+
+	Subcommands: []cli.Command{
+		metadataSetCmd,
+	},
+}
+
+var metadataSetCmd = cli.Command{
+	Name:      "set",
+	Usage:     "add or replace metadata entries of a registered instance",
+	ArgsUsage: "key=value [key=value...]",
+
+	Flags: []cli.Flag{
+		instanceFlag,
+		endpointsFlag,
+	},
+
+	Action: func(c *cli.Context) error {
+		instance := getInstance(c, "set")
+		endpoints := getEndpoints(c, "set")
+		metadata := getMetadata(c)
+
+		log.Printf("Updating metadata for instance '%s' of application '%s'... \n", instance.ID, instance.AppName)
+		client := eureka.NewClient(endpoints)
+		if err := client.UpdateMetadata(instance, metadata); err != nil {
+			log.Printf("Error updating metadata: %s\n", err)
+			return err
+		}
+
+		log.Println("Success")
+		return nil
+	},
+}
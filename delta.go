@@ -0,0 +1,102 @@
+package eureka
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Hashcode computes the apps hashcode the same way Eureka does, i.e. by
+// counting the instances per status, e.g. DOWN_2_UP_5_. It can be used to
+// verify that a locally maintained copy of the registry is in sync.
+func Hashcode(apps []*App) string {
+	counts := map[string]int{}
+	for _, a := range apps {
+		for _, i := range a.Instances {
+			counts[i.Status.String()]++
+		}
+	}
+
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, s)
+	}
+	sort.Strings(statuses)
+
+	var hashcode string
+	for _, s := range statuses {
+		hashcode += fmt.Sprintf("%s_%d_", s, counts[s])
+	}
+
+	return hashcode
+}
+
+// ApplyDelta applies the changes reported by a delta query to the registry
+// snapshot r and returns the resulting snapshot. The snapshot r is left
+// untouched. If the hashcode of the result does not match the one reported by
+// the delta, the result is returned along with ErrHashcodeMismatch and should
+// be replaced by a full fetch.
+func (r *AppsResponse) ApplyDelta(delta *AppsResponse) (*AppsResponse, error) {
+	index := map[string]int{}
+	apps := make([]*App, 0, len(r.Apps))
+
+	for _, a := range r.Apps {
+		index[strings.ToUpper(a.Name)] = len(apps)
+		apps = append(apps, &App{
+			XMLName:   a.XMLName,
+			Name:      a.Name,
+			Instances: append([]*Instance(nil), a.Instances...),
+		})
+	}
+
+	for _, da := range delta.Apps {
+		name := strings.ToUpper(da.Name)
+
+		for _, di := range da.Instances {
+			pos, found := index[name]
+
+			if di.ActionType == ActionTypeDeleted {
+				if found {
+					apps[pos].Instances = removeInstance(apps[pos].Instances, di.ID)
+				}
+				continue
+			}
+
+			if !found {
+				index[name] = len(apps)
+				apps = append(apps, &App{Name: da.Name})
+				pos = len(apps) - 1
+			}
+
+			apps[pos].Instances = append(removeInstance(apps[pos].Instances, di.ID), di)
+		}
+	}
+
+	result := &AppsResponse{
+		VersionDelta: delta.VersionDelta,
+		Hashcode:     delta.Hashcode,
+		Apps:         make([]*App, 0, len(apps)),
+	}
+
+	// apps without instances are no longer registered
+	for _, a := range apps {
+		if len(a.Instances) > 0 {
+			result.Apps = append(result.Apps, a)
+		}
+	}
+
+	if computed := Hashcode(result.Apps); computed != delta.Hashcode {
+		return result, ErrHashcodeMismatch
+	}
+
+	return result, nil
+}
+
+func removeInstance(instances []*Instance, id string) []*Instance {
+	for n, i := range instances {
+		if i.ID == id {
+			return append(instances[:n:n], instances[n+1:]...)
+		}
+	}
+	return instances
+}
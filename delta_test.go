@@ -0,0 +1,135 @@
+package eureka_test
+
+import (
+	"encoding/xml"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/virajago/go-scs-eureka"
+)
+
+var _ = Describe("delta", func() {
+	instance := func(id string, status eureka.Status, action eureka.ActionType) *eureka.Instance {
+		return &eureka.Instance{ID: id, Status: status, ActionType: action}
+	}
+
+	Describe("Hashcode", func() {
+		It("counts instances per status in alphabetical order", func() {
+			apps := []*eureka.App{
+				{Name: "one", Instances: []*eureka.Instance{
+					instance("a", eureka.StatusUp, eureka.ActionTypeNone),
+					instance("b", eureka.StatusDown, eureka.ActionTypeNone),
+				}},
+				{Name: "two", Instances: []*eureka.Instance{
+					instance("c", eureka.StatusUp, eureka.ActionTypeNone),
+					instance("d", eureka.StatusDown, eureka.ActionTypeNone),
+					instance("e", eureka.StatusUp, eureka.ActionTypeNone),
+				}},
+			}
+
+			Expect(eureka.Hashcode(apps)).To(Equal("DOWN_2_UP_3_"))
+		})
+
+		It("returns an empty hashcode for an empty registry", func() {
+			Expect(eureka.Hashcode(nil)).To(BeEmpty())
+		})
+	})
+
+	Describe(".ApplyDelta", func() {
+		var snapshot *eureka.AppsResponse
+
+		BeforeEach(func() {
+			snapshot = &eureka.AppsResponse{
+				VersionDelta: 1,
+				Hashcode:     "UP_3_",
+				Apps: []*eureka.App{
+					{Name: "ONE", Instances: []*eureka.Instance{
+						instance("a", eureka.StatusUp, eureka.ActionTypeNone),
+						instance("b", eureka.StatusUp, eureka.ActionTypeNone),
+					}},
+					{Name: "TWO", Instances: []*eureka.Instance{
+						instance("c", eureka.StatusUp, eureka.ActionTypeNone),
+					}},
+				},
+			}
+		})
+
+		It("applies added, modified and deleted instances", func() {
+			delta := &eureka.AppsResponse{
+				VersionDelta: 2,
+				Hashcode:     "DOWN_1_UP_2_",
+				Apps: []*eureka.App{
+					{Name: "ONE", Instances: []*eureka.Instance{
+						instance("a", eureka.StatusDown, eureka.ActionTypeModified),
+						instance("b", eureka.StatusUp, eureka.ActionTypeDeleted),
+					}},
+					{Name: "TWO", Instances: []*eureka.Instance{
+						instance("c", eureka.StatusUp, eureka.ActionTypeDeleted),
+					}},
+					{Name: "THREE", Instances: []*eureka.Instance{
+						instance("d", eureka.StatusUp, eureka.ActionTypeAdded),
+						instance("e", eureka.StatusUp, eureka.ActionTypeAdded),
+					}},
+				},
+			}
+
+			result, err := snapshot.ApplyDelta(delta)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.VersionDelta).To(Equal(2))
+			Expect(result.Hashcode).To(Equal("DOWN_1_UP_2_"))
+			Expect(result.Apps).To(HaveLen(2))
+
+			Expect(result.Apps[0].Name).To(Equal("ONE"))
+			Expect(result.Apps[0].Instances).To(ConsistOf(delta.Apps[0].Instances[0]))
+
+			Expect(result.Apps[1].Name).To(Equal("THREE"))
+			Expect(result.Apps[1].Instances).To(Equal(delta.Apps[2].Instances))
+		})
+
+		It("does not modify the original snapshot", func() {
+			delta := &eureka.AppsResponse{
+				Hashcode: "UP_2_",
+				Apps: []*eureka.App{
+					{Name: "ONE", Instances: []*eureka.Instance{
+						instance("a", eureka.StatusUp, eureka.ActionTypeDeleted),
+					}},
+				},
+			}
+
+			_, err := snapshot.ApplyDelta(delta)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshot.Apps[0].Instances).To(HaveLen(2))
+			Expect(snapshot.Apps[0].Instances[0].ID).To(Equal("a"))
+		})
+
+		It("reports a hashcode mismatch", func() {
+			delta := &eureka.AppsResponse{
+				Hashcode: "UP_5_",
+				Apps: []*eureka.App{
+					{Name: "ONE", Instances: []*eureka.Instance{
+						instance("x", eureka.StatusUp, eureka.ActionTypeAdded),
+					}},
+				},
+			}
+
+			_, err := snapshot.ApplyDelta(delta)
+			Expect(err).To(Equal(eureka.ErrHashcodeMismatch))
+		})
+	})
+
+	Describe("ActionType", func() {
+		It("is decoded from XML", func() {
+			var i eureka.Instance
+			err := xml.Unmarshal([]byte(`<instance><actionType>MODIFIED</actionType></instance>`), &i)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(i.ActionType).To(Equal(eureka.ActionTypeModified))
+		})
+
+		It("is omitted for instances that are not part of a delta", func() {
+			data, err := xml.Marshal(eureka.Instance{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).ToNot(ContainSubstring("actionType"))
+		})
+	})
+})
@@ -177,3 +177,43 @@ func (s *Status) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	*s, err = ParseStatus(str)
 	return err
 }
+
+var actionTypeNames = []string{
+	"",
+	"ADDED",
+	"MODIFIED",
+	"DELETED",
+}
+
+func ParseActionType(name string) (ActionType, error) {
+	for i, n := range actionTypeNames {
+		if n == name {
+			return ActionType(i), nil
+		}
+	}
+
+	return ActionTypeNone, fmt.Errorf("Unknown action type '%s'", name)
+}
+
+func (a ActionType) String() string {
+	if int(a) >= len(actionTypeNames) {
+		a = ActionTypeNone
+	}
+
+	return actionTypeNames[a]
+}
+
+func (a ActionType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(a.String(), start)
+}
+
+func (a *ActionType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var str string
+	if err := d.DecodeElement(&str, &start); err != nil {
+		return err
+	}
+
+	var err error
+	*a, err = ParseActionType(str)
+	return err
+}
@@ -0,0 +1,378 @@
+package eureka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var dataCenterClasses = []string{
+	"com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo",
+	"com.netflix.appinfo.AmazonInfo",
+}
+
+// emptyMetadataClass is used by Eureka to mark empty metadata maps.
+const emptyMetadataClass = "java.util.Collections$EmptyMap"
+
+func (dct DataCenterType) MarshalJSON() ([]byte, error) {
+	if int(dct) >= len(dataCenterTypes) {
+		return nil, fmt.Errorf("Unknown datacenter type code: %d", dct)
+	}
+	return json.Marshal(dataCenterTypes[dct])
+}
+
+func (dct *DataCenterType) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	for i, n := range dataCenterTypes {
+		if n == str {
+			*dct = DataCenterType(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Unknown datacenter type: %s", str)
+}
+
+type dataCenterJSON struct {
+	Class    string          `json:"@class"`
+	Type     DataCenterType  `json:"name"`
+	Metadata *AmazonMetadata `json:"metadata,omitempty"`
+}
+
+func (dc DataCenter) MarshalJSON() ([]byte, error) {
+	if int(dc.Type) >= len(dataCenterClasses) {
+		return nil, fmt.Errorf("Unknown datacenter type code: %d", dc.Type)
+	}
+
+	aux := dataCenterJSON{
+		Class: dataCenterClasses[dc.Type],
+		Type:  dc.Type,
+	}
+
+	if dc.Type == DataCenterTypeAmazon || dc.Metadata != (AmazonMetadata{}) {
+		aux.Metadata = &dc.Metadata
+	}
+
+	return json.Marshal(aux)
+}
+
+func (dc *DataCenter) UnmarshalJSON(data []byte) error {
+	var aux dataCenterJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	dc.Type = aux.Type
+	dc.Metadata = AmazonMetadata{}
+	if aux.Metadata != nil {
+		dc.Metadata = *aux.Metadata
+	}
+
+	return nil
+}
+
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	if len(m) == 0 {
+		return json.Marshal(map[string]string{"@class": emptyMetadataClass})
+	}
+	return json.Marshal(map[string]string(m))
+}
+
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	aux := make(map[string]string)
+	for key, value := range raw {
+		if key == "@class" {
+			continue
+		}
+
+		var str string
+		if err := json.Unmarshal(value, &str); err != nil {
+			// Eureka does not enforce string values, keep the literal
+			str = string(value)
+		}
+		aux[key] = str
+	}
+
+	*m = aux
+
+	return nil
+}
+
+type portJSON struct {
+	Value   flexInt    `json:"$"`
+	Enabled flexString `json:"@enabled"`
+}
+
+func (p Port) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Value   uint16 `json:"$"`
+		Enabled string `json:"@enabled"`
+	}{uint16(p), strconv.FormatBool(p != 0)})
+}
+
+func (p *Port) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	// older servers render ports as plain numbers
+	if len(data) > 0 && data[0] != '{' {
+		var value flexInt
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*p = Port(value)
+		return nil
+	}
+
+	var aux portJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	*p = Port(aux.Value)
+
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(time.Duration(d).Seconds()))
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds flexInt
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+
+	*d = Duration(time.Duration(seconds) * time.Second)
+
+	return nil
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	if time.Time(t).IsZero() {
+		return json.Marshal(0)
+	}
+
+	epoch := int64(time.Time(t).UnixNano() / int64(time.Millisecond))
+	return json.Marshal(epoch)
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	var epoch flexInt
+	if err := json.Unmarshal(data, &epoch); err != nil {
+		return err
+	}
+
+	*t = Time(time.Unix(0, int64(epoch)*int64(time.Millisecond)))
+
+	return nil
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	if str == "" {
+		*s = StatusUnknown
+		return nil
+	}
+
+	var err error
+	*s, err = ParseStatus(str)
+	return err
+}
+
+func (a ActionType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a *ActionType) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	var err error
+	*a, err = ParseActionType(str)
+	return err
+}
+
+func (a *App) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Name      string       `json:"name"`
+		Instances instanceList `json:"instance"`
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	a.Name = aux.Name
+	a.Instances = aux.Instances
+
+	return nil
+}
+
+type appsResponseJSON struct {
+	VersionDelta flexString `json:"versions__delta"`
+	Hashcode     string     `json:"apps__hashcode"`
+	Apps         appList    `json:"application"`
+}
+
+func (r AppsResponse) MarshalJSON() ([]byte, error) {
+	apps := appList(r.Apps)
+	if apps == nil {
+		apps = appList{}
+	}
+
+	return json.Marshal(appsResponseJSON{
+		VersionDelta: flexString(strconv.Itoa(r.VersionDelta)),
+		Hashcode:     r.Hashcode,
+		Apps:         apps,
+	})
+}
+
+func (r *AppsResponse) UnmarshalJSON(data []byte) error {
+	var aux appsResponseJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.VersionDelta = 0
+	if aux.VersionDelta != "" {
+		delta, err := strconv.Atoi(string(aux.VersionDelta))
+		if err != nil {
+			return fmt.Errorf("Invalid versions delta '%s'", aux.VersionDelta)
+		}
+		r.VersionDelta = delta
+	}
+
+	r.Hashcode = aux.Hashcode
+	r.Apps = aux.Apps
+
+	return nil
+}
+
+// instanceList accepts either a single instance or an array of instances,
+// Eureka renders single element lists without the array.
+type instanceList []*Instance
+
+func (l *instanceList) UnmarshalJSON(data []byte) error {
+	var list []*Instance
+	if err := unmarshalJSONList(data, &list, func() error {
+		i := new(Instance)
+		list = []*Instance{i}
+		return json.Unmarshal(data, i)
+	}); err != nil {
+		return err
+	}
+
+	*l = list
+	return nil
+}
+
+// appList accepts either a single app or an array of apps.
+type appList []*App
+
+func (l *appList) UnmarshalJSON(data []byte) error {
+	var list []*App
+	if err := unmarshalJSONList(data, &list, func() error {
+		a := new(App)
+		list = []*App{a}
+		return json.Unmarshal(data, a)
+	}); err != nil {
+		return err
+	}
+
+	*l = list
+	return nil
+}
+
+func unmarshalJSONList(data []byte, list interface{}, single func() error) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		return single()
+	}
+	return json.Unmarshal(data, list)
+}
+
+// flexInt accepts integers rendered either as JSON numbers or strings.
+type flexInt int64
+
+func (i *flexInt) UnmarshalJSON(data []byte) error {
+	str := string(bytes.Trim(bytes.TrimSpace(data), `"`))
+	if str == "" || str == "null" {
+		*i = 0
+		return nil
+	}
+
+	v, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid integer %s", data)
+	}
+
+	*i = flexInt(v)
+	return nil
+}
+
+// flexString accepts strings as well as numbers and booleans.
+type flexString string
+
+func (s *flexString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = flexString(str)
+		return nil
+	}
+
+	if string(data) == "null" {
+		*s = ""
+		return nil
+	}
+
+	*s = flexString(data)
+	return nil
+}
+
+// marshalJSONRoot marshals v wrapped in an object with a single root key, the
+// way Eureka expects request bodies.
+func marshalJSONRoot(root string, v interface{}) ([]byte, error) {
+	if root == "" {
+		return json.Marshal(v)
+	}
+	return json.Marshal(map[string]interface{}{root: v})
+}
+
+// unmarshalJSONRoot unmarshals data into v, unwrapping the given root key if
+// present.
+func unmarshalJSONRoot(data []byte, root string, v interface{}) error {
+	var wrapped map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapped); err == nil && root != "" && len(wrapped) == 1 {
+		if inner, found := wrapped[root]; found {
+			data = inner
+		}
+	}
+
+	return json.Unmarshal(data, v)
+}
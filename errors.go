@@ -0,0 +1,131 @@
+package eureka
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/virajago/go-scs-eureka/retry"
+)
+
+var (
+	// ErrNotFound is matched by errors.Is for responses with status code 404,
+	// e.g. when the requested app or instance is not registered.
+	ErrNotFound = errors.New("Not found")
+
+	// ErrConflict is matched by errors.Is for responses with status code 409.
+	ErrConflict = errors.New("Conflict")
+
+	// ErrThrottled is matched by errors.Is for responses indicating that the
+	// server is rate limiting requests, i.e. status code 429, or 503 along
+	// with a Retry-After header.
+	ErrThrottled = errors.New("Throttled")
+
+	// ErrHashcodeMismatch is returned if applying a delta does not result in
+	// the apps hashcode reported by the Eureka server.
+	ErrHashcodeMismatch = errors.New("Apps hashcode mismatch")
+)
+
+// maxErrorBodyLen limits the number of bytes of a response body that are
+// retained in an HTTPError.
+const maxErrorBodyLen = 512
+
+// HTTPError is returned when the Eureka server responds with an unexpected
+// status code. Passwords are masked in its URL and Endpoint.
+type HTTPError struct {
+	Method     string
+	URL        string
+	Endpoint   string
+	StatusCode int
+	Body       string
+
+	// RetryAfter is the delay the server asked for in its Retry-After header
+	// before sending the request again, if any.
+	RetryAfter time.Duration
+}
+
+func newHTTPError(req *http.Request, endpoint string, resp *http.Response) *HTTPError {
+	return &HTTPError{
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		Endpoint:   retry.Redact(endpoint),
+		StatusCode: resp.StatusCode,
+		Body:       readBodyExcerpt(resp.Body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("Unexpected response code %d for %s %s", e.StatusCode, e.Method, e.URL)
+	switch {
+	case e.throttled() && e.RetryAfter > 0:
+		msg = fmt.Sprintf("%s (throttled, retry after %s)", msg, e.RetryAfter)
+	case e.throttled():
+		msg = fmt.Sprintf("%s (throttled)", msg)
+	}
+	if e.Body != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Body)
+	}
+	return msg
+}
+
+// HTTPStatus returns the status code of the response. It allows the retry
+// package to classify the error.
+func (e *HTTPError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// ThrottleDelay returns the delay the server asked for before retrying. It
+// allows the retry package to honor the Retry-After header.
+func (e *HTTPError) ThrottleDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// Is reports whether the error matches one of the sentinel errors defined by
+// this package.
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrThrottled:
+		return e.throttled()
+	}
+	return false
+}
+
+func (e *HTTPError) throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusServiceUnavailable && e.RetryAfter > 0
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+func readBodyExcerpt(body io.Reader) string {
+	data, _ := ioutil.ReadAll(io.LimitReader(body, maxErrorBodyLen))
+	return strings.TrimSpace(string(data))
+}
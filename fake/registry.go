@@ -4,10 +4,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/emicklei/go-restful"
 
-	"github.com/st3v/go-eureka"
+	"github.com/virajago/go-scs-eureka"
 )
 
 type registry struct {
@@ -36,7 +37,10 @@ func (r *registry) HTTPServer(addr string, debug bool) *http.Server {
 	s.Route(s.GET("/apps/{app-name}/{instance-id}").To(r.appInstance))
 	s.Route(s.PUT("/apps/{app-name}/{instance-id}/status").To(r.statusOverride))
 	s.Route(s.DELETE("/apps/{app-name}/{instance-id}/status").To(r.removeStatusOverride))
+	s.Route(s.PUT("/apps/{app-name}/{instance-id}/metadata").To(r.updateMetadata))
 	s.Route(s.GET("/instances/{instance-id}").To(r.instance))
+	s.Route(s.GET("/vips/{vip-address}").To(r.vip))
+	s.Route(s.GET("/svips/{svip-address}").To(r.secureVIP))
 
 	return &http.Server{
 		Addr:    addr,
@@ -128,6 +132,44 @@ func (r *registry) app(req *restful.Request, resp *restful.Response) {
 	resp.WriteEntity(app)
 }
 
+func (r *registry) vip(req *restful.Request, resp *restful.Response) {
+	vip := req.PathParameter("vip-address")
+	resp.WriteEntity(r.filter(func(i *eureka.Instance) bool {
+		return matchVIP(i.VIPAddr, vip)
+	}))
+}
+
+func (r *registry) secureVIP(req *restful.Request, resp *restful.Response) {
+	svip := req.PathParameter("svip-address")
+	resp.WriteEntity(r.filter(func(i *eureka.Instance) bool {
+		return matchVIP(i.SecureVIPAddr, svip)
+	}))
+}
+
+func (r *registry) filter(match func(*eureka.Instance) bool) eureka.AppsResponse {
+	result := eureka.AppsResponse{
+		Apps: make([]*eureka.App, 0),
+	}
+
+	for _, app := range r.apps {
+		var instances []*eureka.Instance
+		for _, i := range app.Instances {
+			if match(i) {
+				instances = append(instances, i)
+			}
+		}
+
+		if len(instances) > 0 {
+			result.Apps = append(result.Apps, &eureka.App{
+				Name:      app.Name,
+				Instances: instances,
+			})
+		}
+	}
+
+	return result
+}
+
 func (r *registry) heartbeat(req *restful.Request, resp *restful.Response) {
 	resp.AddHeader("Content-Type", "text/plain")
 
@@ -210,6 +252,31 @@ func (r *registry) removeStatusOverride(req *restful.Request, resp *restful.Resp
 	instance.StatusOverride = eureka.StatusUnknown
 }
 
+func (r *registry) updateMetadata(req *restful.Request, resp *restful.Response) {
+	resp.AddHeader("Content-Type", "text/plain")
+
+	name := req.PathParameter("app-name")
+	instanceID := req.PathParameter("instance-id")
+
+	instance, found := r.findAppInstance(name, instanceID)
+	if !found {
+		resp.WriteErrorString(http.StatusNotFound, "Instance not registered")
+		return
+	}
+
+	metadata := eureka.Metadata{}
+	for k, v := range instance.Metadata {
+		metadata[k] = v
+	}
+
+	for k := range req.Request.URL.Query() {
+		metadata[k] = req.QueryParameter(k)
+	}
+
+	instance.Metadata = metadata
+	resp.WriteHeader(http.StatusOK)
+}
+
 func (r *registry) findAppInstance(appName, instanceID string) (*eureka.Instance, bool) {
 	if app, found := r.apps[appName]; found {
 		return findInstance(instanceID, map[string]*eureka.App{app.Name: app})
@@ -218,6 +285,16 @@ func (r *registry) findAppInstance(appName, instanceID string) (*eureka.Instance
 	return nil, false
 }
 
+// matchVIP checks if vip is contained in a comma-separated list of addresses.
+func matchVIP(addrs, vip string) bool {
+	for _, addr := range strings.Split(addrs, ",") {
+		if strings.EqualFold(strings.TrimSpace(addr), vip) {
+			return true
+		}
+	}
+	return false
+}
+
 func findInstance(instanceID string, apps map[string]*eureka.App) (*eureka.Instance, bool) {
 	for _, a := range apps {
 		for _, i := range a.Instances {
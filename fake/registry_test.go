@@ -0,0 +1,107 @@
+package fake_test
+
+import (
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/virajago/go-scs-eureka"
+	"github.com/virajago/go-scs-eureka/fake"
+	"github.com/virajago/go-scs-eureka/retry"
+)
+
+var _ = Describe("registry", func() {
+	var (
+		server *httptest.Server
+		client *eureka.Client
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(fake.NewRegistry().HTTPServer("", false).Handler)
+		client = eureka.NewClient(
+			[]string{server.URL},
+			eureka.RetryLimit(retry.NoRetries()),
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("VIPs", func() {
+		var one, two, three *eureka.Instance
+
+		BeforeEach(func() {
+			one = &eureka.Instance{ID: "one", AppName: "a", VIPAddr: "shared,vip-a", SecureVIPAddr: "secure-a"}
+			two = &eureka.Instance{ID: "two", AppName: "a", VIPAddr: "vip-a"}
+			three = &eureka.Instance{ID: "three", AppName: "b", VIPAddr: "shared", SecureVIPAddr: "secure-b"}
+
+			for _, i := range []*eureka.Instance{one, two, three} {
+				Expect(client.Register(i)).To(Succeed())
+			}
+		})
+
+		ids := func(instances []*eureka.Instance) []string {
+			result := make([]string, 0, len(instances))
+			for _, i := range instances {
+				result = append(result, i.ID)
+			}
+			return result
+		}
+
+		It("serves instances by VIP", func() {
+			instances, err := client.VIP("vip-a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids(instances)).To(ConsistOf("one", "two"))
+
+			instances, err = client.VIP("shared")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids(instances)).To(ConsistOf("one", "three"))
+		})
+
+		It("serves instances by secure VIP", func() {
+			instances, err := client.SecureVIP("secure-b")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids(instances)).To(ConsistOf("three"))
+		})
+
+		It("returns no instances for unknown VIPs", func() {
+			instances, err := client.VIP("unknown")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(BeEmpty())
+		})
+	})
+
+	Describe("metadata", func() {
+		var instance *eureka.Instance
+
+		BeforeEach(func() {
+			instance = &eureka.Instance{
+				ID:       "one",
+				AppName:  "a",
+				Metadata: eureka.Metadata{"weight": "1", "zone": "z1"},
+			}
+			Expect(client.Register(instance)).To(Succeed())
+		})
+
+		It("updates metadata entries in place", func() {
+			err := client.UpdateMetadata(instance, eureka.Metadata{"weight": "10", "version": "2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			actual, err := client.AppInstance("a", "one")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual.Metadata).To(Equal(eureka.Metadata{
+				"weight":  "10",
+				"version": "2",
+				"zone":    "z1",
+			}))
+		})
+
+		It("fails for unknown instances", func() {
+			unknown := &eureka.Instance{ID: "unknown", AppName: "a"}
+			err := client.UpdateMetadata(unknown, eureka.Metadata{"weight": "10"})
+			Expect(err).To(MatchError(eureka.ErrNotFound))
+		})
+	})
+})
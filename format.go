@@ -0,0 +1,57 @@
+package eureka
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+)
+
+// Format defines the wire format used to talk to the Eureka server.
+type Format uint8
+
+const (
+	// FormatXML encodes requests and decodes responses as XML.
+	FormatXML Format = iota
+
+	// FormatJSON encodes requests and decodes responses as JSON.
+	FormatJSON
+)
+
+func (f Format) contentType() string {
+	if f == FormatJSON {
+		return "application/json"
+	}
+	return "application/xml"
+}
+
+func (f Format) marshal(v interface{}) ([]byte, error) {
+	if f == FormatJSON {
+		return marshalJSONRoot(jsonRoot(v), v)
+	}
+	return xml.Marshal(v)
+}
+
+func (f Format) decode(r io.Reader, v interface{}) error {
+	if f == FormatJSON {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return unmarshalJSONRoot(data, jsonRoot(v), v)
+	}
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// jsonRoot returns the name of the root key Eureka uses to wrap the JSON
+// representation of v, or an empty string if v is not wrapped.
+func jsonRoot(v interface{}) string {
+	switch v.(type) {
+	case *Instance:
+		return "instance"
+	case *App:
+		return "application"
+	case *AppsResponse:
+		return "applications"
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package eureka
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// Handler is notified about the changes observed by a watcher, see
+// WatchHandler. Each handler is called from its own goroutine, one change at a
+// time and in the order the changes have been observed. A slow handler does not
+// delay other handlers or polling, changes pending for it are coalesced per
+// instance instead.
+type Handler interface {
+	OnRegistered(instance *Instance)
+	OnUpdated(old, new *Instance)
+	OnDeregistered(instance *Instance)
+}
+
+// HandlerFuncs allows ordinary functions to be used as handlers. Changes
+// without a function are ignored.
+type HandlerFuncs struct {
+	RegisteredFunc   func(instance *Instance)
+	UpdatedFunc      func(old, new *Instance)
+	DeregisteredFunc func(instance *Instance)
+}
+
+// OnRegistered calls RegisteredFunc(instance), if set.
+func (h HandlerFuncs) OnRegistered(instance *Instance) {
+	if h.RegisteredFunc != nil {
+		h.RegisteredFunc(instance)
+	}
+}
+
+// OnUpdated calls UpdatedFunc(old, new), if set.
+func (h HandlerFuncs) OnUpdated(old, new *Instance) {
+	if h.UpdatedFunc != nil {
+		h.UpdatedFunc(old, new)
+	}
+}
+
+// OnDeregistered calls DeregisteredFunc(instance), if set.
+func (h HandlerFuncs) OnDeregistered(instance *Instance) {
+	if h.DeregisteredFunc != nil {
+		h.DeregisteredFunc(instance)
+	}
+}
+
+// change describes an observed change of an instance. old is nil for newly
+// registered instances, new is nil for deregistered ones.
+type change struct {
+	eventType EventType
+	old       *Instance
+	new       *Instance
+}
+
+// event returns the event describing the change.
+func (c change) event() Event {
+	if c.eventType == EventInstanceDeregistered {
+		return Event{c.eventType, c.old}
+	}
+	return Event{c.eventType, c.new}
+}
+
+// listener queues changes for a handler and calls it in the background.
+type listener struct {
+	handler Handler
+	report  func(error)
+	count   func(coalesced uint)
+	pending *changeQueue
+}
+
+func newListener(handler Handler, report func(error), count func(coalesced uint)) *listener {
+	return &listener{
+		handler: handler,
+		report:  report,
+		count:   count,
+		pending: newChangeQueue(),
+	}
+}
+
+func (l *listener) push(key string, c change) {
+	l.count(l.pending.push(key, c))
+}
+
+// run calls the handler until the watcher is stopped. Pending changes are
+// discarded once stopped.
+func (l *listener) run(ctx context.Context) {
+	for {
+		c, ok := l.pending.pop()
+		if !ok {
+			select {
+			case <-l.pending.ready:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		l.handle(c)
+	}
+}
+
+// handle passes a change to the handler. A panicking handler is reported
+// rather than taking down the watcher.
+func (l *listener) handle(c change) {
+	defer func() {
+		if r := recover(); r != nil {
+			l.report(fmt.Errorf("Watch handler panicked: %v", r))
+		}
+	}()
+
+	switch c.eventType {
+	case EventInstanceRegistered:
+		l.handler.OnRegistered(c.new)
+	case EventInstanceUpdated:
+		l.handler.OnUpdated(c.old, c.new)
+	case EventInstanceDeregistered:
+		l.handler.OnDeregistered(c.old)
+	}
+}
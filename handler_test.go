@@ -0,0 +1,172 @@
+package eureka
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		interval = 10 * time.Millisecond
+		one      = &Instance{ID: "one", HostName: "one.example.com"}
+		two      = &Instance{ID: "two", HostName: "two.example.com"}
+		updated  = &Instance{ID: "one", HostName: "updated.example.com"}
+
+		registry *mockRegistry
+		handler  *recordingHandler
+		watcher  *Watcher
+	)
+
+	BeforeEach(func() {
+		registry = newMockRegistry()
+		registry.Register(&App{Name: "app", Instances: []*Instance{one}})
+
+		handler = &recordingHandler{}
+	})
+
+	AfterEach(func() {
+		watcher.Stop()
+	})
+
+	It("reports changes in order", func() {
+		watcher = newWatcher(registry, interval, WatchHandler(handler))
+		Eventually(handler.Changes).Should(HaveLen(1))
+
+		registry.Register(&App{Name: "app", Instances: []*Instance{updated, two}})
+		Eventually(handler.Changes).Should(HaveLen(3))
+
+		registry.Register(&App{Name: "app", Instances: []*Instance{two}})
+		Eventually(handler.Changes).Should(HaveLen(4))
+
+		Expect(handler.Changes()).To(Equal([]string{
+			"registered one.example.com",
+			"updated one.example.com -> updated.example.com",
+			"registered two.example.com",
+			"deregistered updated.example.com",
+		}))
+		Expect(watcher.Events()).ToNot(Receive())
+	})
+
+	It("reports changes to every handler", func() {
+		other := &recordingHandler{}
+		watcher = newWatcher(registry, interval, WatchHandler(handler), WatchHandler(other))
+
+		Eventually(handler.Changes).Should(ConsistOf("registered one.example.com"))
+		Eventually(other.Changes).Should(ConsistOf("registered one.example.com"))
+	})
+
+	It("is not held up by other handlers", func() {
+		blocked := make(chan struct{})
+		defer close(blocked)
+
+		slow := HandlerFuncs{
+			RegisteredFunc: func(*Instance) { <-blocked },
+		}
+		watcher = newWatcher(registry, interval, WatchHandler(slow), WatchHandler(handler))
+		Eventually(handler.Changes).Should(HaveLen(1))
+
+		registry.Register(&App{Name: "app", Instances: []*Instance{two}})
+		Eventually(handler.Changes).Should(HaveLen(3))
+	})
+
+	It("isolates panicking handlers", func() {
+		panicking := HandlerFuncs{
+			RegisteredFunc: func(*Instance) { panic("oops") },
+		}
+		watcher = newWatcher(registry, interval, WatchHandler(panicking), WatchHandler(handler))
+
+		Eventually(watcher.Errors()).Should(Receive(MatchError("Watch handler panicked: oops")))
+		Eventually(handler.Changes).Should(HaveLen(1))
+
+		registry.Register(&App{Name: "app", Instances: []*Instance{one, two}})
+		Eventually(watcher.Errors()).Should(Receive(MatchError("Watch handler panicked: oops")))
+		Eventually(handler.Changes).Should(HaveLen(2))
+	})
+
+	It("coalesces the changes pending for a slow handler", func() {
+		var (
+			three    = &Instance{ID: "three", HostName: "three.example.com"}
+			moved    = &Instance{ID: "two", HostName: "moved.example.com"}
+			released = make(chan struct{})
+			blocked  = make(chan struct{})
+		)
+
+		slow := HandlerFuncs{
+			RegisteredFunc: func(i *Instance) {
+				if i == one {
+					close(blocked)
+					<-released
+				}
+				handler.OnRegistered(i)
+			},
+			UpdatedFunc: handler.OnUpdated,
+		}
+		watcher = newWatcher(registry, interval, WatchHandler(slow))
+		Eventually(blocked).Should(BeClosed())
+
+		polled := func(n int) {
+			calls := registry.Calls("Apps")
+			Eventually(func() int { return registry.Calls("Apps") }).Should(BeNumerically(">=", calls+n))
+		}
+
+		registry.Register(&App{Name: "app", Instances: []*Instance{one, two, three}})
+		polled(2)
+		registry.Register(&App{Name: "app", Instances: []*Instance{one, moved}})
+		polled(2)
+
+		Expect(watcher.Coalesced()).To(Equal(uint(3)))
+		close(released)
+
+		Eventually(handler.Changes).Should(HaveLen(2))
+		Consistently(handler.Changes).Should(Equal([]string{
+			"registered one.example.com",
+			"registered moved.example.com",
+		}))
+	})
+})
+
+var _ = Describe("HandlerFuncs", func() {
+	It("ignores changes without a function", func() {
+		h := HandlerFuncs{}
+
+		Expect(func() {
+			h.OnRegistered(&Instance{})
+			h.OnUpdated(&Instance{}, &Instance{})
+			h.OnDeregistered(&Instance{})
+		}).ToNot(Panic())
+	})
+})
+
+type recordingHandler struct {
+	mtx     sync.Mutex
+	changes []string
+}
+
+func (h *recordingHandler) record(change string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.changes = append(h.changes, change)
+}
+
+func (h *recordingHandler) Changes() []string {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return append([]string(nil), h.changes...)
+}
+
+func (h *recordingHandler) OnRegistered(i *Instance) {
+	h.record("registered " + i.HostName)
+}
+
+func (h *recordingHandler) OnUpdated(old, new *Instance) {
+	h.record("updated " + old.HostName + " -> " + new.HostName)
+}
+
+func (h *recordingHandler) OnDeregistered(i *Instance) {
+	h.record("deregistered " + i.HostName)
+}
@@ -0,0 +1,143 @@
+package eureka
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Logger is used by the client to log what it is doing. Its methods take a
+// message followed by alternating keys and values, which makes *slog.Logger a
+// valid Logger. The client does not log anything unless a logger is set, see
+// the Logging option.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Redacted replaces the values of sensitive fields in logs.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys lists the normalized keys whose values are redacted.
+var sensitiveKeys = map[string]bool{
+	"secret":         true,
+	"clientsecret":   true,
+	"password":       true,
+	"passwd":         true,
+	"token":          true,
+	"accesstoken":    true,
+	"refreshtoken":   true,
+	"authorization":  true,
+	"credentials":    true,
+	"apikey":         true,
+	"vcapservices":   true,
+	"proxyauthorize": true,
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// redactingLogger redacts sensitive fields before passing them on.
+type redactingLogger struct {
+	logger Logger
+}
+
+func (l redactingLogger) Debug(msg string, args ...interface{}) {
+	l.logger.Debug(msg, redact(args)...)
+}
+
+func (l redactingLogger) Info(msg string, args ...interface{}) {
+	l.logger.Info(msg, redact(args)...)
+}
+
+func (l redactingLogger) Warn(msg string, args ...interface{}) {
+	l.logger.Warn(msg, redact(args)...)
+}
+
+func (l redactingLogger) Error(msg string, args ...interface{}) {
+	l.logger.Error(msg, redact(args)...)
+}
+
+// redact returns a copy of the key-value pairs with the values of sensitive
+// keys replaced and passwords removed from URLs, including URLs contained in
+// errors.
+func redact(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	copy(redacted, args)
+
+	for i := 0; i+1 < len(redacted); i += 2 {
+		key, ok := redacted[i].(string)
+		if !ok {
+			continue
+		}
+
+		if isSensitive(key) {
+			redacted[i+1] = Redacted
+			continue
+		}
+
+		redacted[i+1] = redactURL(redacted[i+1])
+	}
+
+	return redacted
+}
+
+func isSensitive(key string) bool {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+
+	return sensitiveKeys[normalized] ||
+		strings.HasSuffix(normalized, "secret") ||
+		strings.HasSuffix(normalized, "password")
+}
+
+func redactURL(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *url.URL:
+		if v != nil && v.User != nil {
+			return v.Redacted()
+		}
+	case string:
+		if !strings.Contains(v, "@") {
+			return value
+		}
+
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+	case error:
+		if msg := v.Error(); strings.Contains(msg, "@") {
+			if redacted := redactURLs(msg); redacted != msg {
+				return redacted
+			}
+		}
+	}
+
+	return value
+}
+
+// redactURLs removes passwords from the URLs contained in a message.
+func redactURLs(msg string) string {
+	words := strings.Fields(msg)
+	for _, w := range words {
+		if !strings.Contains(w, "://") || !strings.Contains(w, "@") {
+			continue
+		}
+
+		// URLs might be quoted or followed by punctuation
+		trimmed := strings.Trim(w, "\"'`,;:()[]<>")
+		if u, err := url.Parse(trimmed); err == nil && u.User != nil {
+			msg = strings.Replace(msg, trimmed, u.Redacted(), -1)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/virajago/go-scs-eureka"
+)
+
+// DefaultNamespace defines the default namespace of all metrics.
+const DefaultNamespace = "eureka"
+
+var registrationStates = []eureka.RegistrationState{
+	eureka.RegistrationStatePending,
+	eureka.RegistrationStateRegistered,
+	eureka.RegistrationStateFailing,
+	eureka.RegistrationStateStopped,
+}
+
+// Option can be used to configure Metrics.
+type Option func(*options)
+
+type options struct {
+	namespace string
+	buckets   []float64
+}
+
+// Namespace sets the namespace of all metrics. Defaults to DefaultNamespace.
+func Namespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// Buckets sets the buckets of the request latency histogram. Defaults to
+// prometheus.DefBuckets.
+func Buckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// Metrics is a prometheus.Collector for the Eureka client. It implements
+// eureka.Observer to record the requests sent by a client, see
+// eureka.AttemptObserver. Registrations and caches have to be tracked
+// explicitly.
+type Metrics struct {
+	requests   *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	retries    *prometheus.CounterVec
+	heartbeats *prometheus.CounterVec
+
+	registrationState *prometheus.Desc
+	cacheAge          *prometheus.Desc
+	cacheStale        *prometheus.Desc
+
+	mtx           sync.RWMutex
+	registrations map[instanceKey]*eureka.Registration
+	caches        map[string]*eureka.Cache
+}
+
+// instanceKey identifies the instance of a registration, i.e. its labels.
+type instanceKey struct {
+	app string
+	id  string
+}
+
+func keyOf(r *eureka.Registration) instanceKey {
+	return instanceKey{r.Instance().AppName, r.Instance().ID}
+}
+
+// New returns a new, unregistered collector.
+func New(opts ...Option) *Metrics {
+	o := &options{
+		namespace: DefaultNamespace,
+		buckets:   prometheus.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "client",
+			Name:      "requests_total",
+			Help:      "Number of requests sent to Eureka, including retries.",
+		}, []string{"operation", "endpoint", "status"}),
+
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: "client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests sent to Eureka.",
+			Buckets:   o.buckets,
+		}, []string{"operation", "endpoint", "status"}),
+
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "client",
+			Name:      "retries_total",
+			Help:      "Number of retried requests sent to Eureka.",
+		}, []string{"operation", "endpoint"}),
+
+		heartbeats: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "client",
+			Name:      "heartbeat_attempts_total",
+			Help:      "Number of heartbeat attempts sent to Eureka by result, including retries.",
+		}, []string{"result"}),
+
+		registrationState: prometheus.NewDesc(
+			prometheus.BuildFQName(o.namespace, "registration", "state"),
+			"State of a managed registration, 1 for the current state.",
+			[]string{"app", "instance", "state"}, nil,
+		),
+
+		cacheAge: prometheus.NewDesc(
+			prometheus.BuildFQName(o.namespace, "cache", "age_seconds"),
+			"Time since the last successful refresh of a registry cache.",
+			[]string{"cache"}, nil,
+		),
+
+		cacheStale: prometheus.NewDesc(
+			prometheus.BuildFQName(o.namespace, "cache", "stale"),
+			"Whether a registry cache is serving outdated data.",
+			[]string{"cache"}, nil,
+		),
+
+		registrations: map[instanceKey]*eureka.Registration{},
+		caches:        map[string]*eureka.Cache{},
+	}
+}
+
+// ObserveAttempt records a request sent by the client.
+func (m *Metrics) ObserveAttempt(_ context.Context, a eureka.Attempt) {
+	status := "error"
+	if a.StatusCode != 0 {
+		status = strconv.Itoa(a.StatusCode)
+	}
+
+	m.requests.WithLabelValues(a.Operation, a.Endpoint, status).Inc()
+	m.latency.WithLabelValues(a.Operation, a.Endpoint, status).Observe(a.Duration.Seconds())
+
+	if a.Number > 0 {
+		m.retries.WithLabelValues(a.Operation, a.Endpoint).Inc()
+	}
+
+	if a.Operation == "Heartbeat" {
+		result := "success"
+		if a.Err != nil {
+			result = "failure"
+		}
+		m.heartbeats.WithLabelValues(result).Inc()
+	}
+}
+
+// TrackRegistration exports the state of the given registration. It replaces
+// any registration tracked for the same instance.
+func (m *Metrics) TrackRegistration(r *eureka.Registration) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.registrations[keyOf(r)] = r
+}
+
+// UntrackRegistration stops exporting the state of the given registration.
+func (m *Metrics) UntrackRegistration(r *eureka.Registration) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.registrations[keyOf(r)] == r {
+		delete(m.registrations, keyOf(r))
+	}
+}
+
+// TrackCache exports the age of the given cache under the given name. It
+// replaces any cache tracked under the same name.
+func (m *Metrics) TrackCache(name string, c *eureka.Cache) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.caches[name] = c
+}
+
+// UntrackCache stops exporting the age of the cache tracked under the given
+// name.
+func (m *Metrics) UntrackCache(name string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	delete(m.caches, name)
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.latency.Describe(ch)
+	m.retries.Describe(ch)
+	m.heartbeats.Describe(ch)
+
+	ch <- m.registrationState
+	ch <- m.cacheAge
+	ch <- m.cacheStale
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.latency.Collect(ch)
+	m.retries.Collect(ch)
+	m.heartbeats.Collect(ch)
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for _, r := range m.registrations {
+		current := r.State()
+		for _, s := range registrationStates {
+			value := 0.0
+			if s == current {
+				value = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				m.registrationState, prometheus.GaugeValue, value,
+				r.Instance().AppName, r.Instance().ID, s.String(),
+			)
+		}
+	}
+
+	for name, c := range m.caches {
+		stale := 0.0
+		if c.Stale() {
+			stale = 1
+		}
+		ch <- prometheus.MustNewConstMetric(m.cacheStale, prometheus.GaugeValue, stale, name)
+
+		// a cache that has never been refreshed has no age
+		if !c.LastRefresh().IsZero() {
+			ch <- prometheus.MustNewConstMetric(m.cacheAge, prometheus.GaugeValue, c.Age().Seconds(), name)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/net/context"
+
+	"github.com/virajago/go-scs-eureka"
+	"github.com/virajago/go-scs-eureka/metrics"
+	"github.com/virajago/go-scs-eureka/retry"
+)
+
+var _ = Describe("metrics", func() {
+	var (
+		m        *metrics.Metrics
+		registry *prometheus.Registry
+	)
+
+	BeforeEach(func() {
+		m = metrics.New()
+		registry = prometheus.NewPedanticRegistry()
+		Expect(registry.Register(m)).To(Succeed())
+	})
+
+	Describe(".ObserveAttempt", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			m.ObserveAttempt(ctx, eureka.Attempt{Operation: "Heartbeat", Endpoint: "one", StatusCode: 500, Err: errors.New("failed"), Duration: time.Millisecond})
+			m.ObserveAttempt(ctx, eureka.Attempt{Operation: "Heartbeat", Endpoint: "two", Number: 1, StatusCode: 200, Duration: time.Millisecond})
+			m.ObserveAttempt(ctx, eureka.Attempt{Operation: "Apps", Endpoint: "one", Err: errors.New("connection refused")})
+		})
+
+		It("counts requests by operation, endpoint and status", func() {
+			expected := `
+# HELP eureka_client_requests_total Number of requests sent to Eureka, including retries.
+# TYPE eureka_client_requests_total counter
+eureka_client_requests_total{endpoint="one",operation="Apps",status="error"} 1
+eureka_client_requests_total{endpoint="one",operation="Heartbeat",status="500"} 1
+eureka_client_requests_total{endpoint="two",operation="Heartbeat",status="200"} 1
+`
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(expected), "eureka_client_requests_total")).To(Succeed())
+		})
+
+		It("records request latencies", func() {
+			Expect(testutil.GatherAndCount(registry, "eureka_client_request_duration_seconds")).To(Equal(3))
+		})
+
+		It("counts retries", func() {
+			expected := `
+# HELP eureka_client_retries_total Number of retried requests sent to Eureka.
+# TYPE eureka_client_retries_total counter
+eureka_client_retries_total{endpoint="two",operation="Heartbeat"} 1
+`
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(expected), "eureka_client_retries_total")).To(Succeed())
+		})
+
+		It("counts heartbeat attempts by result", func() {
+			expected := `
+# HELP eureka_client_heartbeat_attempts_total Number of heartbeat attempts sent to Eureka by result, including retries.
+# TYPE eureka_client_heartbeat_attempts_total counter
+eureka_client_heartbeat_attempts_total{result="failure"} 1
+eureka_client_heartbeat_attempts_total{result="success"} 1
+`
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(expected), "eureka_client_heartbeat_attempts_total")).To(Succeed())
+		})
+	})
+
+	Context("with a client", func() {
+		var (
+			server *ghttp.Server
+			client *eureka.Client
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			server.AllowUnhandledRequests = true
+
+			client = eureka.NewClient(
+				[]string{server.URL()},
+				eureka.RetryLimit(retry.NoRetries()),
+				eureka.AttemptObserver(m),
+			)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("records the requests sent by the client", func() {
+			server.UnhandledRequestStatusCode = http.StatusNotFound
+			client.App("foo")
+
+			expected := `
+# HELP eureka_client_requests_total Number of requests sent to Eureka, including retries.
+# TYPE eureka_client_requests_total counter
+eureka_client_requests_total{endpoint="` + server.URL() + `",operation="App",status="404"} 1
+`
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(expected), "eureka_client_requests_total")).To(Succeed())
+		})
+
+		It("exports the state of tracked registrations", func() {
+			server.UnhandledRequestStatusCode = http.StatusNoContent
+
+			instance := &eureka.Instance{ID: "id", AppName: "APP"}
+			registration := client.Registration(instance)
+			defer registration.Stop()
+
+			m.TrackRegistration(registration)
+			Eventually(registration.State).Should(Equal(eureka.RegistrationStateRegistered))
+
+			expected := `
+# HELP eureka_registration_state State of a managed registration, 1 for the current state.
+# TYPE eureka_registration_state gauge
+eureka_registration_state{app="APP",instance="id",state="FAILING"} 0
+eureka_registration_state{app="APP",instance="id",state="PENDING"} 0
+eureka_registration_state{app="APP",instance="id",state="REGISTERED"} 1
+eureka_registration_state{app="APP",instance="id",state="STOPPED"} 0
+`
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(expected), "eureka_registration_state")).To(Succeed())
+		})
+
+		It("replaces registrations of the same instance", func() {
+			server.UnhandledRequestStatusCode = http.StatusNoContent
+
+			instance := &eureka.Instance{ID: "id", AppName: "APP"}
+			first := client.Registration(instance)
+			m.TrackRegistration(first)
+			Eventually(first.State).Should(Equal(eureka.RegistrationStateRegistered))
+			first.Stop()
+
+			second := client.Registration(instance)
+			defer second.Stop()
+			m.TrackRegistration(second)
+			Eventually(second.State).Should(Equal(eureka.RegistrationStateRegistered))
+
+			_, err := registry.Gather()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testutil.GatherAndCount(registry, "eureka_registration_state")).To(Equal(4))
+
+			By("keeping the replacement when untracking the replaced registration")
+			m.UntrackRegistration(first)
+			Expect(testutil.GatherAndCount(registry, "eureka_registration_state")).To(Equal(4))
+
+			m.UntrackRegistration(second)
+			Expect(testutil.GatherAndCount(registry, "eureka_registration_state")).To(Equal(0))
+		})
+
+		It("exports the age of tracked caches", func() {
+			server.UnhandledRequestStatusCode = http.StatusInternalServerError
+
+			cache := client.Cache(time.Hour)
+			defer cache.Stop()
+
+			m.TrackCache("registry", cache)
+			Eventually(cache.Err).Should(HaveOccurred())
+
+			By("reporting a cache without data as stale and without age")
+			Expect(testutil.GatherAndCount(registry, "eureka_cache_stale")).To(Equal(1))
+			Expect(testutil.GatherAndCount(registry, "eureka_cache_age_seconds")).To(Equal(0))
+
+			server.UnhandledRequestStatusCode = http.StatusOK
+			server.RouteToHandler("GET", "/apps", ghttp.RespondWith(http.StatusOK, `<applications></applications>`))
+			Expect(cache.Refresh(context.Background())).To(Succeed())
+
+			expected := `
+# HELP eureka_cache_stale Whether a registry cache is serving outdated data.
+# TYPE eureka_cache_stale gauge
+eureka_cache_stale{cache="registry"} 0
+`
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(expected), "eureka_cache_stale")).To(Succeed())
+			Expect(testutil.GatherAndCount(registry, "eureka_cache_age_seconds")).To(Equal(1))
+
+			m.UntrackCache("registry")
+			Expect(testutil.GatherAndCount(registry, "eureka_cache_stale")).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package eureka
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Attempt describes a single attempt of a request sent by the client.
+type Attempt struct {
+	// Operation is the name of the client method that sent the request, e.g.
+	// Register or Apps.
+	Operation string
+
+	// Endpoint is the Eureka endpoint the request was sent to, with its
+	// password masked.
+	Endpoint string
+
+	// Number is the number of the attempt, starting at 0 for the first one.
+	Number uint
+
+	// Delay is the time slept before the attempt.
+	Delay time.Duration
+
+	// Duration is the time the request took.
+	Duration time.Duration
+
+	// StatusCode is the status code of the response, 0 if there was none.
+	StatusCode int
+
+	// Err is the error the attempt failed with, nil if it succeeded.
+	Err error
+}
+
+// Observer is notified about every attempt of every request sent by the
+// client, e.g. to log, record metrics or trace requests. It is called
+// synchronously and must not block.
+type Observer interface {
+	ObserveAttempt(ctx context.Context, attempt Attempt)
+}
+
+// ObserverFunc allows ordinary functions to be used as observers.
+type ObserverFunc func(ctx context.Context, attempt Attempt)
+
+// ObserveAttempt calls f(ctx, attempt).
+func (f ObserverFunc) ObserveAttempt(ctx context.Context, attempt Attempt) {
+	f(ctx, attempt)
+}
+
+// logObserver logs every attempt.
+type logObserver struct {
+	logger Logger
+}
+
+func (o logObserver) ObserveAttempt(_ context.Context, a Attempt) {
+	args := []interface{}{
+		"operation", a.Operation,
+		"endpoint", a.Endpoint,
+		"attempt", a.Number,
+		"status", a.StatusCode,
+		"duration", a.Duration,
+	}
+
+	if a.Err != nil {
+		o.logger.Warn("Eureka request failed", append(args, "error", a.Err)...)
+		return
+	}
+
+	o.logger.Debug("Eureka request succeeded", args...)
+}
@@ -22,16 +22,29 @@ var (
 	// DefaultRetryDelay defines the default delay in-between request retries.
 	DefaultRetryDelay retry.Delay = retry.ConstantDelay(1 * time.Second)
 
-	// DefaultTransport defines the default roundtripper used by the internal http client.
+	// DefaultRetryClassifier defines the default classifier used to decide
+	// whether a failed request should be retried.
+	DefaultRetryClassifier retry.Classifier = retry.TransientErrors
+
+	// DefaultRetryAfterLimit defines the longest delay the client waits before
+	// retrying a throttled request if the server asks for it.
+	DefaultRetryAfterLimit = 10 * time.Second
+
+	// DefaultTransport defines the default roundtripper used by the internal http
+	// client. Every client uses its own copy of the transport, see HTTPTransport.
 	DefaultTransport = &http.Transport{
-		Dial: (&net.Dialer{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
 			Timeout:   5 * time.Second,
 			KeepAlive: 60 * time.Second,
-		}).Dial,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
 		TLSHandshakeTimeout:   5 * time.Second,
 		ResponseHeaderTimeout: 5 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-		MaxIdleConnsPerHost:   1,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
 	}
 
 	// DefaultTimeout defines the default timeout used by the internal http client.
@@ -48,13 +61,59 @@ func HTTPTimeout(t time.Duration) Option {
 	}
 }
 
-// HTTPTransport sets the transport for the internal HTTP client.
+// HTTPTransport sets the transport for the internal HTTP client. The client
+// uses its own copy of the transport, so that options like TLSConfig do not
+// affect other users of the transport.
 func HTTPTransport(t *http.Transport) Option {
 	return func(c *Client) {
 		c.transport = t
 	}
 }
 
+// HTTPRoundTripper sets the round tripper for the internal HTTP client. Unlike
+// HTTPTransport, the round tripper is used as is, i.e. TLSConfig,
+// HTTPConnections and HTTP2 have no effect.
+func HTTPRoundTripper(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.roundTripper = rt
+	}
+}
+
+// HTTPClient sets the HTTP client used to send requests. The client is used as
+// is, i.e. HTTPTimeout and all transport related options have no effect.
+// Oauth2ClientCredentials still applies on top of the client, keeping its
+// timeout, redirect policy and cookie jar.
+func HTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.baseHTTPClient = client
+	}
+}
+
+// HTTPConnections sets the maximum number of idle connections kept open and the
+// maximum number of connections in total per Eureka endpoint. Zero means no
+// limit for maxPerHost.
+func HTTPConnections(maxIdlePerHost, maxPerHost int) Option {
+	return func(c *Client) {
+		c.transportOptions = append(c.transportOptions, func(t *http.Transport) {
+			t.MaxIdleConnsPerHost = maxIdlePerHost
+			t.MaxConnsPerHost = maxPerHost
+		})
+	}
+}
+
+// HTTP2 enables or disables HTTP/2 for the internal HTTP client. HTTP/2 is
+// enabled by default.
+func HTTP2(enabled bool) Option {
+	return func(c *Client) {
+		c.transportOptions = append(c.transportOptions, func(t *http.Transport) {
+			t.ForceAttemptHTTP2 = enabled
+			if !enabled {
+				t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+			}
+		})
+	}
+}
+
 // TLSConfig sets the TLS config for the internal HTTP client.
 func TLSConfig(config *tls.Config) Option {
 	return func(c *Client) {
@@ -96,3 +155,76 @@ func RetryDelay(delay retry.Delay) Option {
 		c.retryDelay = delay
 	}
 }
+
+// RetryClassifier sets the classifier the client uses to decide whether a
+// failed request should be retried.
+func RetryClassifier(classifier retry.Classifier) Option {
+	return func(c *Client) {
+		c.retryClassifier = classifier
+	}
+}
+
+// RetryMaxElapsed instructs the client to stop retrying a request once another
+// attempt would start more than max after the first one.
+func RetryMaxElapsed(max time.Duration) Option {
+	return func(c *Client) {
+		c.retryMaxElapsed = max
+	}
+}
+
+// RetryAfterLimit sets the longest delay the client waits before retrying a
+// throttled request if the server asks for it through the Retry-After header.
+// If the limit is zero, the header is ignored and the retry delay applies.
+func RetryAfterLimit(max time.Duration) Option {
+	return func(c *Client) {
+		c.retryAfterLimit = max
+	}
+}
+
+// RetryBudget instructs the client to limit retries to the given budget. All
+// requests of the client share the budget, which can also be shared between
+// clients.
+func RetryBudget(budget *retry.Budget) Option {
+	return func(c *Client) {
+		c.retryBudget = budget
+	}
+}
+
+// CircuitBreaker instructs the client to track the health of its endpoints
+// with the given breaker and to skip endpoints the breaker has quarantined.
+// The breaker can be shared between clients and queried for diagnostics.
+func CircuitBreaker(breaker *retry.Breaker) Option {
+	return func(c *Client) {
+		c.breaker = breaker
+	}
+}
+
+// AttemptObserver instructs the client to notify the given observer about every
+// attempt of every request. The option can be used multiple times to add
+// several observers.
+func AttemptObserver(observer Observer) Option {
+	return func(c *Client) {
+		c.observers = append(c.observers, observer)
+	}
+}
+
+// Logging sets the logger the client, its registrations and caches log to.
+// Credentials and other sensitive values are redacted before they are passed
+// to the logger. By default, nothing is logged.
+func Logging(logger Logger) Option {
+	return func(c *Client) {
+		if logger == nil {
+			c.logger = nopLogger{}
+			return
+		}
+		c.logger = redactingLogger{logger}
+	}
+}
+
+// WireFormat sets the format the client uses to encode requests and decode
+// responses. Defaults to FormatXML.
+func WireFormat(format Format) Option {
+	return func(c *Client) {
+		c.format = format
+	}
+}
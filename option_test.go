@@ -3,9 +3,11 @@ package eureka
 import (
 	"crypto/tls"
 	"net/http"
+	"net/http/cookiejar"
 	"reflect"
 	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 
 	. "github.com/onsi/ginkgo"
@@ -18,12 +20,20 @@ var _ = Describe("client options", func() {
 	Describe("No option", func() {
 		It("uses the default http client", func() {
 			actual := NewClient([]string{"endpoint"}).httpClient
-			expected := &http.Client{
-				Timeout:   DefaultTimeout,
-				Transport: DefaultTransport,
-			}
+			Expect(actual.Timeout).To(Equal(DefaultTimeout))
+
+			transport, ok := actual.Transport.(*http.Transport)
+			Expect(ok).To(BeTrue())
+			Expect(transport.MaxIdleConnsPerHost).To(Equal(DefaultTransport.MaxIdleConnsPerHost))
+			Expect(transport.ForceAttemptHTTP2).To(BeTrue())
+		})
+
+		It("uses its own copy of the default transport", func() {
+			a := NewClient([]string{"endpoint"}).httpClient.Transport
+			b := NewClient([]string{"endpoint"}).httpClient.Transport
 
-			Expect(actual).To(Equal(expected))
+			Expect(a).ToNot(BeIdenticalTo(DefaultTransport))
+			Expect(a).ToNot(BeIdenticalTo(b))
 		})
 
 		It("uses the default retry selector", func() {
@@ -40,6 +50,16 @@ var _ = Describe("client options", func() {
 			client := NewClient([]string{"endpoint"})
 			Expect(reflect.ValueOf(client.retryDelay)).To(Equal(reflect.ValueOf(DefaultRetryDelay)))
 		})
+
+		It("uses the default retry classifier", func() {
+			client := NewClient([]string{"endpoint"})
+			Expect(reflect.ValueOf(client.retryClassifier)).To(Equal(reflect.ValueOf(DefaultRetryClassifier)))
+		})
+
+		It("uses the default Retry-After limit", func() {
+			client := NewClient([]string{"endpoint"})
+			Expect(client.retryAfterLimit).To(Equal(DefaultRetryAfterLimit))
+		})
 	})
 
 	Describe("HTTPTimeout", func() {
@@ -48,13 +68,23 @@ var _ = Describe("client options", func() {
 			client := NewClient([]string{"endpoint"}, HTTPTimeout(timeout))
 			Expect(client.httpClient.Timeout).To(Equal(timeout))
 		})
+
+		It("applies to clients using oauth2", func() {
+			timeout := 123 * time.Second
+			client := NewClient([]string{"endpoint"}, HTTPTimeout(timeout), Oauth2ClientCredentials("id", "secret", "uri"))
+			Expect(client.httpClient.Timeout).To(Equal(timeout))
+		})
 	})
 
 	Describe("HTTPTransport", func() {
 		It("sets the transport for the internal HTTP client", func() {
-			transport := new(http.Transport)
+			transport := &http.Transport{MaxIdleConns: 42}
 			client := NewClient([]string{"endpoint"}, HTTPTransport(transport))
-			Expect(client.httpClient.Transport).To(BeIdenticalTo(transport))
+
+			actual, ok := client.httpClient.Transport.(*http.Transport)
+			Expect(ok).To(BeTrue())
+			Expect(actual).ToNot(BeIdenticalTo(transport))
+			Expect(actual.MaxIdleConns).To(Equal(42))
 		})
 	})
 
@@ -66,6 +96,84 @@ var _ = Describe("client options", func() {
 			Expect(ok).To(BeTrue())
 			Expect(transport.TLSClientConfig).To(BeIdenticalTo(tlsConfig))
 		})
+
+		It("does not affect other clients", func() {
+			insecure := NewClient([]string{"endpoint"}, TLSConfig(&tls.Config{InsecureSkipVerify: true}))
+			Expect(insecure.httpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+
+			if DefaultTransport.TLSClientConfig != nil {
+				Expect(DefaultTransport.TLSClientConfig.InsecureSkipVerify).To(BeFalse())
+			}
+
+			client := NewClient([]string{"endpoint"})
+			transport := client.httpClient.Transport.(*http.Transport)
+			if transport.TLSClientConfig != nil {
+				Expect(transport.TLSClientConfig.InsecureSkipVerify).To(BeFalse())
+			}
+		})
+	})
+
+	Describe("HTTPRoundTripper", func() {
+		It("uses the round tripper as is", func() {
+			rt := &http.Transport{}
+			client := NewClient([]string{"endpoint"}, HTTPRoundTripper(rt), TLSConfig(&tls.Config{}))
+			Expect(client.httpClient.Transport).To(BeIdenticalTo(rt))
+			Expect(rt.TLSClientConfig).To(BeNil())
+		})
+	})
+
+	Describe("HTTPClient", func() {
+		It("uses the client as is", func() {
+			httpClient := &http.Client{}
+			client := NewClient([]string{"endpoint"}, HTTPClient(httpClient), HTTPTimeout(time.Second))
+			Expect(client.httpClient).To(BeIdenticalTo(httpClient))
+			Expect(httpClient.Timeout).To(BeZero())
+		})
+
+		It("is wrapped for oauth2", func() {
+			httpClient := &http.Client{Transport: &http.Transport{}}
+			client := NewClient([]string{"endpoint"}, HTTPClient(httpClient), Oauth2ClientCredentials("id", "secret", "uri"))
+
+			transport, ok := client.httpClient.Transport.(*oauth2.Transport)
+			Expect(ok).To(BeTrue())
+			Expect(transport.Base).To(BeIdenticalTo(httpClient.Transport))
+		})
+
+		It("keeps the settings of the client when wrapped for oauth2", func() {
+			jar, err := cookiejar.New(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			httpClient := &http.Client{
+				Timeout: time.Second,
+				Jar:     jar,
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+			client := NewClient([]string{"endpoint"}, HTTPClient(httpClient), Oauth2ClientCredentials("id", "secret", "uri"))
+
+			Expect(client.httpClient.Timeout).To(Equal(time.Second))
+			Expect(client.httpClient.Jar).To(BeIdenticalTo(jar))
+			Expect(client.httpClient.CheckRedirect).ToNot(BeNil())
+		})
+	})
+
+	Describe("HTTPConnections", func() {
+		It("sets the connection limits", func() {
+			client := NewClient([]string{"endpoint"}, HTTPConnections(20, 50))
+			transport := client.httpClient.Transport.(*http.Transport)
+			Expect(transport.MaxIdleConnsPerHost).To(Equal(20))
+			Expect(transport.MaxConnsPerHost).To(Equal(50))
+		})
+	})
+
+	Describe("HTTP2", func() {
+		It("disables HTTP/2", func() {
+			client := NewClient([]string{"endpoint"}, HTTP2(false))
+			transport := client.httpClient.Transport.(*http.Transport)
+			Expect(transport.ForceAttemptHTTP2).To(BeFalse())
+			Expect(transport.TLSNextProto).ToNot(BeNil())
+		})
 	})
 
 	Describe("Oauth2ClientCredentials", func() {
@@ -74,7 +182,8 @@ var _ = Describe("client options", func() {
 			client := NewClient([]string{"endpoint"}, Oauth2ClientCredentials(id, secret, uri, scope))
 			transport, ok := client.httpClient.Transport.(*oauth2.Transport)
 			Expect(ok).To(BeTrue())
-			Expect(transport.Base).To(BeIdenticalTo(DefaultTransport))
+			Expect(transport.Base).To(BeAssignableToTypeOf(DefaultTransport))
+			Expect(transport.Base).ToNot(BeIdenticalTo(DefaultTransport))
 		})
 	})
 
@@ -106,4 +215,67 @@ var _ = Describe("client options", func() {
 			Expect(reflect.ValueOf(client.retryDelay)).To(Equal(reflect.ValueOf(delay)))
 		})
 	})
+
+	Describe("RetryClassifier", func() {
+		var classifier retry.Classifier = func(_ error) bool { return false }
+
+		It("sets retry classifier", func() {
+			client := NewClient([]string{"endpoint"}, RetryClassifier(classifier))
+			Expect(reflect.ValueOf(client.retryClassifier)).To(Equal(reflect.ValueOf(classifier)))
+		})
+	})
+
+	Describe("RetryMaxElapsed", func() {
+		It("sets the retry time limit", func() {
+			client := NewClient([]string{"endpoint"}, RetryMaxElapsed(time.Minute))
+			Expect(client.retryMaxElapsed).To(Equal(time.Minute))
+		})
+	})
+
+	Describe("RetryAfterLimit", func() {
+		It("sets the Retry-After limit", func() {
+			client := NewClient([]string{"endpoint"}, RetryAfterLimit(time.Minute))
+			Expect(client.retryAfterLimit).To(Equal(time.Minute))
+		})
+	})
+
+	Describe("RetryBudget", func() {
+		It("sets the retry budget", func() {
+			budget := retry.NewBudget(0.1, 10)
+			client := NewClient([]string{"endpoint"}, RetryBudget(budget))
+			Expect(client.retryBudget).To(BeIdenticalTo(budget))
+		})
+	})
+
+	Describe("CircuitBreaker", func() {
+		It("sets the breaker", func() {
+			breaker := retry.NewBreaker(3, time.Minute)
+			client := NewClient([]string{"endpoint"}, CircuitBreaker(breaker))
+			Expect(client.breaker).To(BeIdenticalTo(breaker))
+		})
+	})
+
+	Describe("AttemptObserver", func() {
+		It("adds observers", func() {
+			var (
+				a = ObserverFunc(func(context.Context, Attempt) {})
+				b = ObserverFunc(func(context.Context, Attempt) {})
+			)
+
+			client := NewClient([]string{"endpoint"}, AttemptObserver(a), AttemptObserver(b))
+			Expect(client.observers).To(HaveLen(2))
+		})
+	})
+
+	Describe("WireFormat", func() {
+		It("defaults to XML", func() {
+			client := NewClient([]string{"endpoint"})
+			Expect(client.format).To(Equal(FormatXML))
+		})
+
+		It("sets the wire format", func() {
+			client := NewClient([]string{"endpoint"}, WireFormat(FormatJSON))
+			Expect(client.format).To(Equal(FormatJSON))
+		})
+	})
 })
@@ -0,0 +1,281 @@
+package eureka
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/virajago/go-scs-eureka/retry"
+)
+
+var (
+	// DefaultRenewalInterval defines the interval at which heartbeats are sent
+	// if the instance does not specify LeaseInfo.RenewalInterval.
+	DefaultRenewalInterval = 30 * time.Second
+
+	// DefaultRegistrationBackoff defines the default delay before retrying a
+	// failed registration or heartbeat. The delay never exceeds the renewal
+	// interval.
+	DefaultRegistrationBackoff retry.Delay = retry.ExponentialBackoff(1 * time.Second)
+)
+
+// RegistrationState describes the state of a managed registration.
+type RegistrationState uint8
+
+const (
+	// RegistrationStatePending indicates that the instance is not registered
+	// yet, or has to be registered again after the server reported it unknown.
+	RegistrationStatePending RegistrationState = iota
+
+	// RegistrationStateRegistered indicates that the last registration or
+	// heartbeat succeeded.
+	RegistrationStateRegistered
+
+	// RegistrationStateFailing indicates that the last heartbeat failed. The
+	// instance might still be registered but risks being evicted.
+	RegistrationStateFailing
+
+	// RegistrationStateStopped indicates that the registration has been
+	// stopped and the instance has been deregistered.
+	RegistrationStateStopped
+)
+
+var registrationStateNames = []string{
+	"PENDING",
+	"REGISTERED",
+	"FAILING",
+	"STOPPED",
+}
+
+func (s RegistrationState) String() string {
+	if int(s) >= len(registrationStateNames) {
+		return "UNKNOWN"
+	}
+	return registrationStateNames[s]
+}
+
+// RegistrationOption can be used to configure a Registration.
+type RegistrationOption func(*Registration)
+
+// RegistrationBackoff sets the delay before retrying a failed registration or
+// heartbeat. The delay is passed the number of consecutive failures.
+func RegistrationBackoff(delay retry.Delay) RegistrationOption {
+	return func(r *Registration) {
+		r.backoff = delay
+	}
+}
+
+// Registration keeps an instance registered with Eureka. It registers the
+// instance, sends heartbeats at the instance's renewal interval, registers the
+// instance again if the server no longer knows it, and deregisters it once
+// stopped. Failures never end the registration, they are retried with a
+// backoff instead.
+type Registration struct {
+	registrar registrar
+	instance  *Instance
+	interval  time.Duration
+	backoff   retry.Delay
+	logger    Logger
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mtx           sync.RWMutex
+	state         RegistrationState
+	lastErr       error
+	lastHeartbeat time.Time
+	registered    bool
+}
+
+type registrar interface {
+	RegisterContext(ctx context.Context, instance *Instance) error
+	HeartbeatContext(ctx context.Context, instance *Instance) error
+	DeregisterContext(ctx context.Context, instance *Instance) error
+}
+
+func newRegistration(registrar registrar, instance *Instance, logger Logger, options ...RegistrationOption) *Registration {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	interval := time.Duration(instance.LeaseInfo.RenewalInterval)
+	if interval <= 0 {
+		interval = DefaultRenewalInterval
+	}
+
+	r := &Registration{
+		registrar: registrar,
+		instance:  instance,
+		interval:  interval,
+		backoff:   DefaultRegistrationBackoff,
+		logger:    logger,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	for _, opt := range options {
+		opt(r)
+	}
+
+	go r.run(ctx)
+
+	return r
+}
+
+// Stop ends the registration and deregisters the instance.
+func (r *Registration) Stop() error {
+	return r.StopContext(context.Background())
+}
+
+// StopContext is like Stop but aborts the deregistration once ctx is done.
+func (r *Registration) StopContext(ctx context.Context) error {
+	r.cancel()
+	<-r.done
+
+	r.mtx.RLock()
+	registered := r.registered
+	r.mtx.RUnlock()
+
+	var err error
+	if registered {
+		err = r.registrar.DeregisterContext(ctx, r.instance)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.state = RegistrationStateStopped
+	r.registered = false
+	r.lastErr = err
+
+	if err != nil {
+		r.logger.Warn("Failed to deregister instance", r.logArgs("error", err)...)
+	} else if registered {
+		r.logger.Info("Deregistered instance", r.logArgs()...)
+	}
+
+	return err
+}
+
+// Instance returns the managed instance.
+func (r *Registration) Instance() *Instance {
+	return r.instance
+}
+
+// State returns the current state of the registration.
+func (r *Registration) State() RegistrationState {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.state
+}
+
+// Err returns the error of the most recent registration, heartbeat or
+// deregistration, if any.
+func (r *Registration) Err() error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.lastErr
+}
+
+// LastHeartbeat returns the time of the last successful registration or
+// heartbeat.
+func (r *Registration) LastHeartbeat() time.Time {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.lastHeartbeat
+}
+
+func (r *Registration) run(ctx context.Context) {
+	defer close(r.done)
+
+	var failures uint
+
+	for {
+		wait := r.interval
+
+		evicted, err := r.renew(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case evicted:
+			// the server forgot about us, register again right away
+			failures = 0
+			wait = 0
+		case err != nil:
+			failures++
+			if d := r.backoff(failures); d < wait {
+				wait = d
+			}
+		default:
+			failures = 0
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// renew registers the instance or sends a heartbeat, depending on whether the
+// instance is currently registered. It reports whether a heartbeat revealed
+// that the server no longer knows the instance.
+func (r *Registration) renew(ctx context.Context) (bool, error) {
+	r.mtx.RLock()
+	registered := r.registered
+	r.mtx.RUnlock()
+
+	var err error
+	if registered {
+		err = r.registrar.HeartbeatContext(ctx, r.instance)
+	} else {
+		err = r.registrar.RegisterContext(ctx, r.instance)
+	}
+
+	if ctx.Err() != nil {
+		if err == nil && !registered {
+			// registered while being stopped, make sure to deregister
+			r.mtx.Lock()
+			r.registered = true
+			r.mtx.Unlock()
+		}
+		return false, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.lastErr = err
+
+	switch {
+	case err == nil:
+		if !registered {
+			r.logger.Info("Registered instance", r.logArgs()...)
+		}
+		r.registered = true
+		r.state = RegistrationStateRegistered
+		r.lastHeartbeat = time.Now()
+	case registered && errors.Is(err, ErrNotFound):
+		r.logger.Warn("Instance unknown to Eureka, registering again", r.logArgs()...)
+		r.registered = false
+		r.state = RegistrationStatePending
+		return true, err
+	case registered:
+		r.logger.Warn("Failed to send heartbeat", r.logArgs("error", err)...)
+		r.state = RegistrationStateFailing
+	default:
+		r.logger.Warn("Failed to register instance", r.logArgs("error", err)...)
+		r.state = RegistrationStatePending
+	}
+
+	return false, err
+}
+
+func (r *Registration) logArgs(args ...interface{}) []interface{} {
+	return append([]interface{}{"app", r.instance.AppName, "instance", r.instance.ID}, args...)
+}
@@ -0,0 +1,188 @@
+package eureka
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+
+	"github.com/virajago/go-scs-eureka/retry"
+)
+
+var _ = Describe("Registration", func() {
+	var (
+		instance     *Instance
+		registrar    *mockRegistrar
+		registration *Registration
+	)
+
+	BeforeEach(func() {
+		instance = &Instance{
+			ID:      "one",
+			AppName: "app",
+			LeaseInfo: Lease{
+				RenewalInterval: Duration(10 * time.Millisecond),
+			},
+		}
+
+		registrar = newMockRegistrar()
+	})
+
+	start := func() {
+		registration = newRegistration(registrar, instance, nopLogger{}, RegistrationBackoff(retry.ConstantDelay(time.Millisecond)))
+	}
+
+	AfterEach(func() {
+		registration.Stop()
+	})
+
+	It("registers the instance", func() {
+		start()
+		Eventually(registration.State).Should(Equal(RegistrationStateRegistered))
+		Expect(registrar.Calls("register")).To(Equal(1))
+	})
+
+	It("sends heartbeats at the renewal interval", func() {
+		start()
+		Eventually(func() int { return registrar.Calls("heartbeat") }).Should(BeNumerically(">=", 3))
+		Expect(registrar.Calls("register")).To(Equal(1))
+		Expect(registration.LastHeartbeat()).To(BeTemporally("~", time.Now(), time.Second))
+	})
+
+	It("registers again if the instance is unknown to the server", func() {
+		start()
+		Eventually(registration.State).Should(Equal(RegistrationStateRegistered))
+
+		registrar.Fail("heartbeat", &HTTPError{StatusCode: 404})
+
+		Eventually(func() int { return registrar.Calls("register") }).Should(BeNumerically(">=", 2))
+	})
+
+	It("keeps retrying failed registrations", func() {
+		someErr := errors.New("some error")
+		registrar.Fail("register", someErr)
+		start()
+
+		Eventually(func() int { return registrar.Calls("register") }).Should(BeNumerically(">=", 3))
+		Expect(registration.State()).To(Equal(RegistrationStatePending))
+		Expect(registration.Err()).To(Equal(someErr))
+
+		registrar.Fail("register", nil)
+		Eventually(registration.State).Should(Equal(RegistrationStateRegistered))
+		Expect(registration.Err()).ToNot(HaveOccurred())
+	})
+
+	It("reports failing heartbeats", func() {
+		start()
+		Eventually(registration.State).Should(Equal(RegistrationStateRegistered))
+
+		registrar.Fail("heartbeat", errors.New("some error"))
+		Eventually(registration.State).Should(Equal(RegistrationStateFailing))
+
+		registrar.Fail("heartbeat", nil)
+		Eventually(registration.State).Should(Equal(RegistrationStateRegistered))
+	})
+
+	It("deregisters the instance when stopped", func() {
+		start()
+		Eventually(registration.State).Should(Equal(RegistrationStateRegistered))
+
+		Expect(registration.Stop()).To(Succeed())
+		Expect(registration.State()).To(Equal(RegistrationStateStopped))
+		Expect(registrar.Calls("deregister")).To(Equal(1))
+	})
+
+	It("does not deregister an instance that never got registered", func() {
+		registrar.Fail("register", errors.New("some error"))
+		start()
+
+		Eventually(func() int { return registrar.Calls("register") }).Should(BeNumerically(">=", 1))
+
+		Expect(registration.Stop()).To(Succeed())
+		Expect(registrar.Calls("deregister")).To(BeZero())
+	})
+
+	It("deregisters an instance that got registered while stopping", func() {
+		release := registrar.Hold("register")
+		start()
+
+		Eventually(func() int { return registrar.Calls("register") }).Should(Equal(1))
+
+		stopped := make(chan error)
+		go func() { stopped <- registration.Stop() }()
+
+		// stopping waits for the registration in progress
+		Consistently(stopped).ShouldNot(Receive())
+		release()
+
+		Eventually(stopped).Should(Receive(BeNil()))
+		Expect(registrar.Calls("deregister")).To(Equal(1))
+	})
+})
+
+type mockRegistrar struct {
+	mtx   sync.Mutex
+	calls map[string]int
+	errs  map[string]error
+	holds map[string]chan struct{}
+}
+
+func newMockRegistrar() *mockRegistrar {
+	return &mockRegistrar{
+		calls: map[string]int{},
+		errs:  map[string]error{},
+		holds: map[string]chan struct{}{},
+	}
+}
+
+// Hold blocks the given call until the returned func is called.
+func (m *mockRegistrar) Hold(call string) func() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	hold := make(chan struct{})
+	m.holds[call] = hold
+	return func() { close(hold) }
+}
+
+func (m *mockRegistrar) Fail(call string, err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.errs[call] = err
+}
+
+func (m *mockRegistrar) Calls(call string) int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.calls[call]
+}
+
+func (m *mockRegistrar) call(name string) error {
+	m.mtx.Lock()
+	m.calls[name]++
+	err := m.errs[name]
+	hold := m.holds[name]
+	m.mtx.Unlock()
+
+	if hold != nil {
+		<-hold
+	}
+	return err
+}
+
+func (m *mockRegistrar) RegisterContext(_ context.Context, _ *Instance) error {
+	return m.call("register")
+}
+
+func (m *mockRegistrar) HeartbeatContext(_ context.Context, _ *Instance) error {
+	return m.call("heartbeat")
+}
+
+func (m *mockRegistrar) DeregisterContext(_ context.Context, _ *Instance) error {
+	return m.call("deregister")
+}
@@ -0,0 +1,188 @@
+package retry
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// BreakerState describes the state of the circuit for a single endpoint.
+type BreakerState uint8
+
+const (
+	// BreakerClosed indicates that the endpoint is healthy.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen indicates that the endpoint is quarantined after failing
+	// repeatedly. It is only selected if no other endpoint is available.
+	BreakerOpen
+
+	// BreakerHalfOpen indicates that the cool-down of a quarantined endpoint
+	// has passed. The endpoint is selected again, the next attempt decides
+	// whether it is closed or opened again.
+	BreakerHalfOpen
+)
+
+var breakerStateNames = []string{
+	"CLOSED",
+	"OPEN",
+	"HALF_OPEN",
+}
+
+func (s BreakerState) String() string {
+	if int(s) >= len(breakerStateNames) {
+		return "UNKNOWN"
+	}
+	return breakerStateNames[s]
+}
+
+// EndpointHealth describes the health of an endpoint as tracked by a Breaker.
+type EndpointHealth struct {
+	Endpoint string
+	State    BreakerState
+
+	// Failures is the number of consecutive failed attempts.
+	Failures uint
+
+	// LastErr is the error of the most recent failed attempt.
+	LastErr error
+
+	// LastFailure is the time of the most recent failed attempt.
+	LastFailure time.Time
+
+	// OpenUntil is the time the cool-down of a quarantined endpoint ends.
+	OpenUntil time.Time
+}
+
+// Breaker tracks the health of endpoints. An endpoint is quarantined after a
+// number of consecutive failed attempts and half-opened again once a cool-down
+// has passed. A single failure of a half-open endpoint quarantines it again, a
+// single success closes it.
+//
+// Attempts are recorded by strategies created with the Track option. The
+// Selector method wraps a selector so that it skips quarantined endpoints.
+type Breaker struct {
+	threshold uint
+	coolDown  time.Duration
+
+	mtx       sync.Mutex
+	endpoints map[string]*EndpointHealth
+}
+
+// NewBreaker returns a breaker that quarantines endpoints for the duration of
+// coolDown after threshold consecutive failures.
+func NewBreaker(threshold uint, coolDown time.Duration) *Breaker {
+	if threshold == 0 {
+		threshold = 1
+	}
+
+	return &Breaker{
+		threshold: threshold,
+		coolDown:  coolDown,
+		endpoints: map[string]*EndpointHealth{},
+	}
+}
+
+// Selector wraps the given selector so that it only picks from endpoints that
+// are not quarantined. If all endpoints are quarantined, it picks from all of
+// them rather than giving up.
+func (b *Breaker) Selector(selector Selector) Selector {
+	return func(endpoints []string) Endpoint {
+		now := time.Now()
+
+		available := make([]string, 0, len(endpoints))
+		for _, e := range endpoints {
+			if b.state(e, now) != BreakerOpen {
+				available = append(available, e)
+			}
+		}
+
+		if len(available) == 0 {
+			return selector(endpoints)
+		}
+
+		return selector(available)
+	}
+}
+
+// Record records the outcome of an attempt against an endpoint. Only errors
+// that indicate a problem with the endpoint, i.e. network errors and server
+// side failures, count as failures. Client side failures count as successes,
+// cancelled and timed out attempts are ignored.
+func (b *Breaker) Record(endpoint string, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	h, found := b.endpoints[endpoint]
+	if !found {
+		h = &EndpointHealth{Endpoint: endpoint}
+		b.endpoints[endpoint] = h
+	}
+
+	if err == nil || !TransientErrors(err) {
+		h.State = BreakerClosed
+		h.Failures = 0
+		h.OpenUntil = time.Time{}
+		return
+	}
+
+	now := time.Now()
+
+	h.Failures++
+	h.LastErr = err
+	h.LastFailure = now
+
+	if h.Failures >= b.threshold || h.State != BreakerClosed {
+		h.State = BreakerOpen
+		h.OpenUntil = now.Add(b.coolDown)
+	}
+}
+
+// Health returns the health of all endpoints the breaker has seen, ordered by
+// endpoint. Passwords are masked in the endpoints returned.
+func (b *Breaker) Health() []EndpointHealth {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := time.Now()
+
+	health := make([]EndpointHealth, 0, len(b.endpoints))
+	for _, h := range b.endpoints {
+		c := *h
+		c.Endpoint = Redact(h.Endpoint)
+		c.State = stateAt(h, now)
+		health = append(health, c)
+	}
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Endpoint < health[j].Endpoint
+	})
+
+	return health
+}
+
+func (b *Breaker) state(endpoint string, now time.Time) BreakerState {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	h, found := b.endpoints[endpoint]
+	if !found {
+		return BreakerClosed
+	}
+
+	return stateAt(h, now)
+}
+
+func stateAt(h *EndpointHealth, now time.Time) BreakerState {
+	if h.State == BreakerOpen && !now.Before(h.OpenUntil) {
+		return BreakerHalfOpen
+	}
+	return h.State
+}
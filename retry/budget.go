@@ -0,0 +1,58 @@
+package retry
+
+import (
+	"sync"
+)
+
+// Budget limits retries to a fraction of all requests. It is a token bucket
+// that every request adds ratio tokens to and every retry takes one token
+// from, so that a struggling server is not hit by a storm of retries. A budget
+// is safe for concurrent use and is meant to be shared by all strategies of a
+// client.
+type Budget struct {
+	ratio float64
+	max   float64
+
+	mtx    sync.Mutex
+	tokens float64
+}
+
+// NewBudget returns a budget that allows retries to make up ratio of all
+// requests, e.g. 0.1 for 10%. Up to burst unused retries can be saved up, the
+// budget starts out full.
+func NewBudget(ratio float64, burst uint) *Budget {
+	return &Budget{
+		ratio:  ratio,
+		max:    float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// Tokens returns the number of retries currently available.
+func (b *Budget) Tokens() float64 {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.tokens
+}
+
+func (b *Budget) deposit() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.tokens += b.ratio; b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+func (b *Budget) withdraw() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Classifier decides whether a failed attempt should be retried.
+type Classifier func(err error) bool
+
+// StatusCoder is implemented by errors that carry the HTTP status code of a
+// failed request.
+type StatusCoder interface {
+	HTTPStatus() int
+}
+
+// Throttler is implemented by errors reporting that the server asked for a
+// delay before the request is sent again, e.g. through a Retry-After header.
+type Throttler interface {
+	ThrottleDelay() time.Duration
+}
+
+// AllErrors retries any error.
+func AllErrors(_ error) bool {
+	return true
+}
+
+// TransientErrors retries network errors, server side (5xx) failures and
+// throttled (429) requests. Other client side (4xx) failures and cancelled
+// contexts are not retried since another attempt would not change the outcome.
+func TransientErrors(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		return sc.HTTPStatus() >= http.StatusInternalServerError ||
+			sc.HTTPStatus() == http.StatusTooManyRequests
+	}
+
+	return true
+}
@@ -0,0 +1,115 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrMaxElapsed is reported as the reason for aborting if a strategy
+	// stopped retrying because its time limit would have been exceeded.
+	ErrMaxElapsed = errors.New("Retry time limit exceeded")
+
+	// ErrBudgetExhausted is reported as the reason for aborting if a strategy
+	// stopped retrying because its retry budget has been used up.
+	ErrBudgetExhausted = errors.New("Retry budget exhausted")
+)
+
+// AttemptError records the failure of a single attempt. The password of the
+// endpoint, if any, is masked.
+type AttemptError struct {
+	Attempt  uint
+	Endpoint string
+	Err      error
+}
+
+func (e *AttemptError) Error() string {
+	return fmt.Sprintf("attempt %d against %s: %s", e.Attempt, e.Endpoint, e.Err)
+}
+
+func (e *AttemptError) Unwrap() error {
+	return e.Err
+}
+
+// Redact masks the password of an endpoint, if any, so that it can be printed
+// safely.
+func Redact(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return endpoint
+}
+
+// Error is returned by a Strategy if none of its attempts succeeded. It lists
+// the failure of every attempt in order.
+type Error struct {
+	Attempts []*AttemptError
+
+	// Aborted is set to the context error if the strategy stopped because its
+	// context was done, or to ErrMaxElapsed or ErrBudgetExhausted if it ran
+	// out of time or budget for retries.
+	Aborted error
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Attempts)+1)
+	for _, a := range e.Attempts {
+		parts = append(parts, a.Error())
+	}
+
+	if e.Aborted != nil {
+		parts = append(parts, fmt.Sprintf("aborted: %s", e.Aborted))
+	}
+
+	return fmt.Sprintf("%d attempt(s) failed: %s", len(e.Attempts), strings.Join(parts, "; "))
+}
+
+// Unwrap allows errors.Is and errors.As to inspect the errors of all attempts
+// as well as the reason for aborting, if any.
+func (e *Error) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	for _, a := range e.Attempts {
+		errs = append(errs, a)
+	}
+
+	if e.Aborted != nil {
+		errs = append(errs, e.Aborted)
+	}
+
+	return errs
+}
+
+// Is reports whether the error of any attempt, or the reason for aborting,
+// matches target. Unlike Unwrap, it is also used by errors.Is on Go versions
+// prior to 1.20.
+func (e *Error) Is(target error) bool {
+	for _, err := range e.Unwrap() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of an attempt, or the reason for aborting, that
+// matches target. Unlike Unwrap, it is also used by errors.As on Go versions
+// prior to 1.20.
+func (e *Error) As(target interface{}) bool {
+	for _, err := range e.Unwrap() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Last returns the error of the most recent attempt, or nil if no attempt has
+// been made.
+func (e *Error) Last() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
@@ -0,0 +1,117 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// JitterOption can be used to configure a jittered Delay.
+type JitterOption func(*jitter)
+
+type jitter struct {
+	rnd *lockedRand
+}
+
+// JitterSource sets the source of randomness used to jitter delays, e.g. a
+// source with a fixed seed to make tests reproducible.
+func JitterSource(src rand.Source) JitterOption {
+	return func(j *jitter) {
+		j.rnd = &lockedRand{rnd: rand.New(src)}
+	}
+}
+
+func newJitter(opts []JitterOption) *jitter {
+	j := &jitter{}
+
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	if j.rnd == nil {
+		j.rnd = &lockedRand{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	}
+
+	return j
+}
+
+// FullJitter randomizes the given delay, i.e. it returns a random delay
+// between zero and the delay returned by delay.
+func FullJitter(delay Delay, opts ...JitterOption) Delay {
+	j := newJitter(opts)
+
+	return func(attempt uint) time.Duration {
+		return j.rnd.duration(0, delay(attempt))
+	}
+}
+
+// EqualJitter keeps half of the given delay and randomizes the other half,
+// i.e. it returns a random delay between half of and the full delay returned
+// by delay.
+func EqualJitter(delay Delay, opts ...JitterOption) Delay {
+	j := newJitter(opts)
+
+	return func(attempt uint) time.Duration {
+		d := delay(attempt)
+		return d/2 + j.rnd.duration(0, d-d/2)
+	}
+}
+
+// DecorrelatedJitter returns delays that grow with every attempt, each one a
+// random delay between base and three times the previous one, but never more
+// than max. The delay before the first retry is drawn as if the previous one
+// was base. The first attempt is not delayed.
+func DecorrelatedJitter(base, max time.Duration, opts ...JitterOption) Delay {
+	j := newJitter(opts)
+
+	return func(attempt uint) time.Duration {
+		if attempt == 0 {
+			return 0
+		}
+
+		// replay the sequence of previous delays rather than keeping state,
+		// the delay is shared by concurrent strategies
+		d := base
+		for i := uint(1); i <= attempt; i++ {
+			upper := time.Duration(math.MaxInt64)
+			if d < upper/3 {
+				upper = 3 * d
+			}
+
+			d = j.rnd.duration(base, upper)
+			if d > max {
+				d = max
+			}
+		}
+
+		return d
+	}
+}
+
+// Cap limits the delays returned by delay to max.
+func Cap(delay Delay, max time.Duration) Delay {
+	return func(attempt uint) time.Duration {
+		if d := delay(attempt); d >= 0 && d < max {
+			return d
+		}
+		return max
+	}
+}
+
+type lockedRand struct {
+	mtx sync.Mutex
+	rnd *rand.Rand
+}
+
+// duration returns a random duration in [min, max).
+func (r *lockedRand) duration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return min + time.Duration(r.rnd.Int63n(int64(max-min)))
+}
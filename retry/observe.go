@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Attempt describes a finished attempt.
+type Attempt struct {
+	// Number is the number of the attempt, starting at 0.
+	Number uint
+
+	// Endpoint is the endpoint of the attempt, with its password masked.
+	Endpoint string
+
+	// Delay is the time slept before the attempt.
+	Delay time.Duration
+
+	// Duration is the time the action took.
+	Duration time.Duration
+
+	// Err is the error the attempt failed with, nil if it succeeded.
+	Err error
+}
+
+// Observer is notified about every attempt made by a strategy, e.g. to log or
+// record metrics. It is called synchronously and must not block.
+type Observer interface {
+	ObserveAttempt(ctx context.Context, attempt Attempt)
+}
+
+// ObserverFunc allows ordinary functions to be used as observers.
+type ObserverFunc func(ctx context.Context, attempt Attempt)
+
+// ObserveAttempt calls f(ctx, attempt).
+func (f ObserverFunc) ObserveAttempt(ctx context.Context, attempt Attempt) {
+	f(ctx, attempt)
+}
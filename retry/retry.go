@@ -1,9 +1,14 @@
 package retry
 
 import (
+	"errors"
 	"math"
 	"math/rand"
+	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/net/context"
 )
 
 type Strategy func(action Action) error
@@ -12,8 +17,61 @@ func (s Strategy) Apply(action Action) error {
 	return s(action)
 }
 
+// ApplyContext applies the strategy to the given action. Once ctx is done,
+// no further attempts are made and ctx.Err() is returned without waiting for
+// any pending delay. An attempt in progress is waited for. Strategies created
+// by NewStrategy stop in the background once their pending delay has passed,
+// other strategies might keep running.
+//
+// Deprecated: ApplyContext is best-effort, bind the context using the Context
+// option instead.
+func (s Strategy) ApplyContext(ctx context.Context, action Action) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var (
+		mtx     sync.Mutex
+		stopped bool
+		result  = make(chan error, 1)
+	)
+
+	go func() {
+		result <- s(func(endpoint string) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			if stopped {
+				return &stoppedError{ctx.Err()}
+			}
+			return action(endpoint)
+		})
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		// wait for an attempt in progress
+		mtx.Lock()
+		stopped = true
+		mtx.Unlock()
+		return ctx.Err()
+	}
+}
+
 type Action func(endpoint string) error
 
+// stoppedError is returned by actions wrapped by ApplyContext once the context
+// is done. Strategies created by NewStrategy stop when they encounter it.
+type stoppedError struct {
+	err error
+}
+
+func (e *stoppedError) Error() string {
+	return e.err.Error()
+}
+
 type Endpoint func(attempt uint) string
 
 type Selector func(endpoints []string) Endpoint
@@ -22,16 +80,215 @@ type Allow func(attempt uint) bool
 
 type Delay func(attempt uint) time.Duration
 
-func NewStrategy(endpoint Endpoint, allow Allow, delay Delay) Strategy {
+// Backoff is like Delay but is also passed the error the previous attempt
+// failed with, nil before the first attempt.
+type Backoff func(attempt uint, prev error) time.Duration
+
+// Option can be used to configure a Strategy.
+type Option func(*options)
+
+type options struct {
+	ctx        context.Context
+	classify   Classifier
+	breaker    *Breaker
+	maxElapsed time.Duration
+	budget     *Budget
+	backoff    Backoff
+	observers  []Observer
+}
+
+// Context binds the strategy to the given context. Once ctx is done, no further
+// attempts are made and any pending delay is interrupted. Observers are passed
+// ctx.
+func Context(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// Classify instructs the strategy to stop retrying as soon as an attempt fails
+// with an error the classifier deems not worth retrying. By default, all
+// errors are retried.
+func Classify(classifier Classifier) Option {
+	return func(o *options) {
+		o.classify = classifier
+	}
+}
+
+// Track instructs the strategy to record the outcome of every attempt with
+// the given breaker.
+func Track(breaker *Breaker) Option {
+	return func(o *options) {
+		o.breaker = breaker
+	}
+}
+
+// MaxElapsed instructs the strategy to stop retrying once another attempt
+// would start more than max after the first one. Attempts in progress are not
+// interrupted, use a context deadline for that.
+func MaxElapsed(max time.Duration) Option {
+	return func(o *options) {
+		o.maxElapsed = max
+	}
+}
+
+// Spend instructs the strategy to only retry as long as the given budget
+// allows it. Budgets are typically shared by many strategies.
+func Spend(budget *Budget) Option {
+	return func(o *options) {
+		o.budget = budget
+	}
+}
+
+// Pace instructs the strategy to compute the delay before each attempt using
+// the given backoff instead of its Delay.
+func Pace(backoff Backoff) Option {
+	return func(o *options) {
+		o.backoff = backoff
+	}
+}
+
+// RetryAfter returns a backoff that waits as long as the server asked for if
+// the previous attempt failed with a Throttler error, but no longer than max.
+// Otherwise, it falls back to delay.
+func RetryAfter(delay Delay, max time.Duration) Backoff {
+	return func(attempt uint, prev error) time.Duration {
+		var t Throttler
+		if errors.As(prev, &t) && t.ThrottleDelay() > 0 {
+			if d := t.ThrottleDelay(); d < max {
+				return d
+			}
+			return max
+		}
+		return delay(attempt)
+	}
+}
+
+// Observe instructs the strategy to notify the given observer about every
+// attempt. The option can be used multiple times to add several observers.
+func Observe(observer Observer) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, observer)
+	}
+}
+
+func NewStrategy(endpoint Endpoint, allow Allow, delay Delay, opts ...Option) Strategy {
+	o := &options{
+		ctx:      context.Background(),
+		classify: AllErrors,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return func(action Action) error {
-		var err error
+		var (
+			ctx    = o.ctx
+			failed []*AttemptError
+			start  = time.Now()
+		)
+
+		for i := uint(0); allow(i) && (i == 0 || len(failed) > 0); i++ {
+			d := o.delay(i, delay, failed)
+
+			if err := o.admit(i, time.Since(start)+d); err != nil {
+				return &Error{Attempts: failed, Aborted: err}
+			}
+
+			if err := sleep(ctx, d); err != nil {
+				return &Error{Attempts: failed, Aborted: err}
+			}
 
-		for i := uint(0); allow(i) && (i == 0 || err != nil); i++ {
-			time.Sleep(delay(i))
-			err = action(endpoint(i))
+			e := endpoint(i)
+			redacted := Redact(e)
+			begin := time.Now()
+			err := action(e)
+			if stopped, ok := err.(*stoppedError); ok {
+				return &Error{Attempts: failed, Aborted: stopped.err}
+			}
+
+			if o.breaker != nil {
+				o.breaker.Record(e, err)
+			}
+
+			for _, obs := range o.observers {
+				obs.ObserveAttempt(ctx, Attempt{
+					Number:   i,
+					Endpoint: redacted,
+					Delay:    d,
+					Duration: time.Since(begin),
+					Err:      err,
+				})
+			}
+
+			if err != nil {
+				failed = append(failed, &AttemptError{i, redacted, err})
+				if !o.classify(err) {
+					break
+				}
+				continue
+			}
+
+			return nil
 		}
 
-		return err
+		if len(failed) > 0 {
+			return &Error{Attempts: failed}
+		}
+
+		return nil
+	}
+}
+
+// delay computes the delay before the given attempt.
+func (o *options) delay(attempt uint, delay Delay, failed []*AttemptError) time.Duration {
+	if o.backoff == nil {
+		return delay(attempt)
+	}
+
+	var prev error
+	if len(failed) > 0 {
+		prev = failed[len(failed)-1].Err
+	}
+
+	return o.backoff(attempt, prev)
+}
+
+// admit decides whether the given attempt may be made, elapsed being the time
+// that will have passed since the first attempt once the attempt is made.
+func (o *options) admit(attempt uint, elapsed time.Duration) error {
+	if attempt == 0 {
+		if o.budget != nil {
+			o.budget.deposit()
+		}
+		return nil
+	}
+
+	if o.maxElapsed > 0 && elapsed > o.maxElapsed {
+		return ErrMaxElapsed
+	}
+
+	if o.budget != nil && !o.budget.withdraw() {
+		return ErrBudgetExhausted
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -48,6 +305,37 @@ func Random(endpoints []string) Endpoint {
 	}
 }
 
+// ZoneAffinity returns a selector that tries the endpoints located in the
+// given zone first and fails over to endpoints in other zones. The order of
+// endpoints within each group is shuffled per request to spread the load.
+// zones maps endpoints to the zone they are located in.
+func ZoneAffinity(zone string, zones map[string]string) Selector {
+	normalized := make(map[string]string, len(zones))
+	for e, z := range zones {
+		normalized[strings.TrimRight(e, " /")] = z
+	}
+
+	return func(endpoints []string) Endpoint {
+		var local, remote []string
+		for _, e := range endpoints {
+			if normalized[strings.TrimRight(e, " /")] == zone {
+				local = append(local, e)
+			} else {
+				remote = append(remote, e)
+			}
+		}
+
+		return RoundRobin(append(shuffle(local), shuffle(remote)...))
+	}
+}
+
+func shuffle(endpoints []string) []string {
+	rand.Shuffle(len(endpoints), func(i, j int) {
+		endpoints[i], endpoints[j] = endpoints[j], endpoints[i]
+	})
+	return endpoints
+}
+
 func NoRetries() Allow {
 	return func(attempt uint) bool {
 		return attempt == 0
@@ -81,6 +369,11 @@ func LinearBackoff(delay time.Duration) Delay {
 
 func ExponentialBackoff(delay time.Duration) Delay {
 	return func(attempt uint) time.Duration {
-		return time.Duration(math.Pow(2.0, float64(attempt))) * delay
+		d := math.Pow(2.0, float64(attempt)) * float64(delay)
+		if d >= math.MaxInt64 {
+			// saturate rather than overflow
+			return math.MaxInt64
+		}
+		return time.Duration(d)
 	}
 }
@@ -1,7 +1,6 @@
 package eureka
 
 import (
-  "fmt"
   "os"
   "encoding/json"
   "encoding/xml"
@@ -32,7 +31,7 @@ const (
     ServiceRegistry = "p-service-registry"
 )
 
-func getAppInstanceInfo() (*CFApp, *Instance, error) {
+func getAppInstanceInfo(logger Logger) (*CFApp, *Instance, error) {
 
   app:= new(CFApp)
   regInstance:= new(Instance)
@@ -45,20 +44,13 @@ func getAppInstanceInfo() (*CFApp, *Instance, error) {
 
   VCAPApplicationEnv := os.Getenv("VCAP_APPLICATION")
 
-  fmt.Println("VCAP port",app.Port)
-  fmt.Println("VCAP IPAddr",app.IPAddr)
-  fmt.Println("VCAP instanceID",app.InstanceID)
-  fmt.Println("Hostname",app.Hostname)
-  fmt.Println("VCAP APPLICATION",VCAPApplicationEnv)
+  logger.Debug("Read CF instance env", "port", app.Port, "ipAddr", app.IPAddr, "instanceID", app.InstanceID, "hostname", app.Hostname)
 
   if err = json.Unmarshal([]byte(VCAPApplicationEnv), app); err != nil {
     return nil,nil,err
   }
 
-  fmt.Println("ApplID",app.ID)
-  fmt.Println("ApplName",app.Name)
-  fmt.Println("ApplURI",app.URIs[0])
-  fmt.Println("Hostname",app.Hostname)
+  logger.Debug("Read VCAP_APPLICATION", "appID", app.ID, "appName", app.Name, "appURIs", app.URIs)
 
   regInstance = &Instance{
       XMLName:        xml.Name{Local: "instance"},
@@ -84,18 +76,16 @@ func getAppInstanceInfo() (*CFApp, *Instance, error) {
 
 }
 
-func getServiceInfo() (*CFService,error){
+func getServiceInfo(logger Logger) (*CFService,error){
 
   var services map[string]interface{}
   service:= new(CFService)
 
   VCAPServicesEnv := os.Getenv("VCAP_SERVICES")
-  fmt.Println("VCAP SERVICES",VCAPServicesEnv)
 
   if err := json.Unmarshal([]byte(VCAPServicesEnv), &services); err != nil {
     return nil,err
   }
-  fmt.Println(services)
   if services[ServiceRegistry] == nil {
     //fmt.Println("Error: Service Registry not bound to application")
     return nil, errors.New("Service Registry not bound to application")
@@ -106,29 +96,26 @@ func getServiceInfo() (*CFService,error){
   service.ClientID = registryCred["client_id"].(string)
   service.TokenURI = registryCred["access_token_uri"].(string)
 
-  fmt.Println("serverURI",service.ServerURI)
-  fmt.Println("clientSecret",service.ClientSecret)
-  fmt.Println("clientID",service.ClientID)
-  fmt.Println("tokenURI",service.TokenURI)
+  logger.Debug("Read service registry credentials", "serverURI", service.ServerURI, "clientID", service.ClientID, "clientSecret", service.ClientSecret, "tokenURI", service.TokenURI)
 
   return service,nil
 }
 
-func GetClientSCS(skip_ssl bool) (*Client,error){
+func GetClientSCS(skip_ssl bool, options ...Option) (*Client,error){
 
-  serviceCred,err := getServiceInfo()
+  serviceCred,err := getServiceInfo(loggerFrom(options))
   if err!=nil {
     //fmt.Println("Error getting CF Service Inst env",err)
     return nil,err
   }
 
   oAuth2Options := Oauth2ClientCredentials(serviceCred.ClientID, serviceCred.ClientSecret, serviceCred.TokenURI)
-  var tlsOption Option
+  options = append([]Option{oAuth2Options}, options...)
   if skip_ssl == true {
-    tlsOption = TLSConfig(&tls.Config{InsecureSkipVerify: true})
+    options = append(options, TLSConfig(&tls.Config{InsecureSkipVerify: true}))
   }
 
-  c := NewClient([]string{serviceCred.ServerURI},oAuth2Options,tlsOption)
+  c := NewClient([]string{serviceCred.ServerURI},options...)
 
   if c==nil {
     return nil,errors.New("Failed to create HTTP client")
@@ -145,22 +132,19 @@ func GetClientSCS(skip_ssl bool) (*Client,error){
   return c,nil
 }
 
-func RegisterSCS(skip_ssl bool) error {
-  fmt.Println("Getting HTTP Client for SCS")
-
-  c,err:=GetClientSCS(skip_ssl)
+func RegisterSCS(skip_ssl bool, options ...Option) error {
+  c,err:=GetClientSCS(skip_ssl, options...)
   if err!=nil {
     //fmt.Println("Error getting CF app env",err)
     return err
   }
 
-  fmt.Println("Getting CF app info")
-  _,regInstance,err:=getAppInstanceInfo()
+  _,regInstance,err:=getAppInstanceInfo(c.logger)
   if err!=nil {
     return err
   }
 
-  fmt.Println("Registering application",regInstance.AppName,"/",regInstance.ID)
+  c.logger.Info("Registering instance", "app", regInstance.AppName, "instance", regInstance.ID)
   err= c.Register(regInstance)
   if err!=nil {
     return err
@@ -169,29 +153,40 @@ func RegisterSCS(skip_ssl bool) error {
   return nil
 }
 
-func SendHearbeatSCS(skip_ssl bool) {
-  fmt.Println("Getting HTTP Client for SCS")
+func SendHearbeatSCS(skip_ssl bool, options ...Option) {
+  logger := loggerFrom(options)
 
-  c,err:=GetClientSCS(skip_ssl)
+  c,err:=GetClientSCS(skip_ssl, options...)
   if err!=nil {
-    fmt.Println("Error:",err)
+    logger.Error("Failed to create client for service registry", "error", err)
     return
   }
 
-  fmt.Println("Getting CF app info")
-  _,regInstance,err:=getAppInstanceInfo()
+  _,regInstance,err:=getAppInstanceInfo(c.logger)
   if err!=nil {
-    fmt.Println("Error:",err)
+    c.logger.Error("Failed to read CF app info", "error", err)
     return
   }
 
   for {
-    fmt.Println("Sending Heartbeat for",regInstance.AppName,"/",regInstance.ID)
+    c.logger.Debug("Sending heartbeat", "app", regInstance.AppName, "instance", regInstance.ID)
     err = c.Heartbeat(regInstance)
     if err!=nil {
-      fmt.Println("Error:",err)
+      c.logger.Error("Failed to send heartbeat", "app", regInstance.AppName, "instance", regInstance.ID, "error", err)
       return
     }
     time.Sleep(time.Second * 30)
   }
 }
+
+// loggerFrom returns the logger set by the given options, so that it can be
+// used before the client is created.
+func loggerFrom(options []Option) Logger {
+  c := &Client{logger: nopLogger{}}
+  for _, opt := range options {
+    if opt != nil {
+      opt(c)
+    }
+  }
+  return c.logger
+}
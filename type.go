@@ -7,23 +7,24 @@ import (
 )
 
 type Instance struct {
-	XMLName        xml.Name   `xml:"instance"`
-	ID             string     `xml:"instanceId"`
-	HostName       string     `xml:"hostName"`
-	AppName        string     `xml:"app"`
-	IPAddr         string     `xml:"ipAddr"`
-	VIPAddr        string     `xml:"vipAddress"`
-	SecureVIPAddr  string     `xml:"secureVipAddress"`
-	Status         Status     `xml:"status"`
-	StatusOverride Status     `xml:"overriddenstatus"`
-	Port           Port       `xml:"port"`
-	SecurePort     Port       `xml:"securePort"`
-	HomePageURL    string     `xml:"homePageUrl"`
-	StatusPageURL  string     `xml:"statusPageUrl"`
-	HealthCheckURL string     `xml:"healthCheckUrl"`
-	DataCenterInfo DataCenter `xml:"dataCenterInfo"`
-	LeaseInfo      Lease      `xml:"leaseInfo"`
-	Metadata       Metadata   `xml:"metadata"`
+	XMLName        xml.Name   `xml:"instance" json:"-"`
+	ID             string     `xml:"instanceId" json:"instanceId"`
+	HostName       string     `xml:"hostName" json:"hostName"`
+	AppName        string     `xml:"app" json:"app"`
+	IPAddr         string     `xml:"ipAddr" json:"ipAddr"`
+	VIPAddr        string     `xml:"vipAddress" json:"vipAddress"`
+	SecureVIPAddr  string     `xml:"secureVipAddress" json:"secureVipAddress"`
+	Status         Status     `xml:"status" json:"status"`
+	StatusOverride Status     `xml:"overriddenstatus" json:"overriddenstatus"`
+	Port           Port       `xml:"port" json:"port"`
+	SecurePort     Port       `xml:"securePort" json:"securePort"`
+	HomePageURL    string     `xml:"homePageUrl" json:"homePageUrl"`
+	StatusPageURL  string     `xml:"statusPageUrl" json:"statusPageUrl"`
+	HealthCheckURL string     `xml:"healthCheckUrl" json:"healthCheckUrl"`
+	DataCenterInfo DataCenter `xml:"dataCenterInfo" json:"dataCenterInfo"`
+	LeaseInfo      Lease      `xml:"leaseInfo" json:"leaseInfo"`
+	Metadata       Metadata   `xml:"metadata" json:"metadata"`
+	ActionType     ActionType `xml:"actionType,omitempty" json:"actionType,omitempty"`
 }
 
 // Equals checks if two instances are the same. Does not compare LeaseInfo.
@@ -45,6 +46,16 @@ func (i *Instance) Equals(other *Instance) bool {
 		i.Metadata.Equals(other.Metadata)
 }
 
+// Zone returns the availability zone of the instance. It is taken from the
+// datacenter metadata if present, or from the "zone" metadata entry used by
+// Spring Cloud otherwise.
+func (i *Instance) Zone() string {
+	if zone := i.DataCenterInfo.Metadata.AvailabilityZone; zone != "" {
+		return zone
+	}
+	return i.Metadata["zone"]
+}
+
 type Port uint16
 
 type Status uint8
@@ -57,9 +68,21 @@ const (
 	StatusUnknown
 )
 
+// ActionType is reported for instances returned by a delta query and
+// describes how the instance changed since the last version.
+type ActionType uint8
+
+const (
+	// ActionTypeNone is used for instances that are not part of a delta.
+	ActionTypeNone ActionType = iota
+	ActionTypeAdded
+	ActionTypeModified
+	ActionTypeDeleted
+)
+
 type DataCenter struct {
-	Type     DataCenterType `xml:"name"`
-	Metadata AmazonMetadata `xml:"metadata"`
+	Type     DataCenterType `xml:"name" json:"name"`
+	Metadata AmazonMetadata `xml:"metadata" json:"metadata"`
 }
 
 type DataCenterType uint8
@@ -70,26 +93,26 @@ const (
 )
 
 type AmazonMetadata struct {
-	HostName         string `xml:"hostname"`
-	PublicHostName   string `xml:"public-hostname"`
-	LocalHostName    string `xml:"local-hostname"`
-	PublicIPV4       string `xml:"public-ipv4"`
-	LocalIPV4        string `xml:"local-ipv4"`
-	AvailabilityZone string `xml:"availability-zone"`
-	InstanceID       string `xml:"instance-id"`
-	InstanceType     string `xml:"instance-type"`
-	AmiID            string `xml:"ami-id"`
-	AmiLaunchIndex   string `xml:"ami-launch-index"`
-	AmiManifestPath  string `xml:"ami-manifest-path"`
+	HostName         string `xml:"hostname" json:"hostname"`
+	PublicHostName   string `xml:"public-hostname" json:"public-hostname"`
+	LocalHostName    string `xml:"local-hostname" json:"local-hostname"`
+	PublicIPV4       string `xml:"public-ipv4" json:"public-ipv4"`
+	LocalIPV4        string `xml:"local-ipv4" json:"local-ipv4"`
+	AvailabilityZone string `xml:"availability-zone" json:"availability-zone"`
+	InstanceID       string `xml:"instance-id" json:"instance-id"`
+	InstanceType     string `xml:"instance-type" json:"instance-type"`
+	AmiID            string `xml:"ami-id" json:"ami-id"`
+	AmiLaunchIndex   string `xml:"ami-launch-index" json:"ami-launch-index"`
+	AmiManifestPath  string `xml:"ami-manifest-path" json:"ami-manifest-path"`
 }
 
 type Lease struct {
-	RenewalInterval  Duration `xml:"renewalIntervalInSecs"`
-	Duration         Duration `xml:"durationInSecs"`
-	RegistrationTime Time     `xml:"registrationTimestamp"`
-	LastRenewalTime  Time     `xml:"lastRenewalTimestamp"`
-	EvictionTime     Time     `xml:"evictionTimestamp"`
-	ServiceUpTime    Time     `xml:"serviceUpTimestamp"`
+	RenewalInterval  Duration `xml:"renewalIntervalInSecs" json:"renewalIntervalInSecs"`
+	Duration         Duration `xml:"durationInSecs" json:"durationInSecs"`
+	RegistrationTime Time     `xml:"registrationTimestamp" json:"registrationTimestamp"`
+	LastRenewalTime  Time     `xml:"lastRenewalTimestamp" json:"lastRenewalTimestamp"`
+	EvictionTime     Time     `xml:"evictionTimestamp" json:"evictionTimestamp"`
+	ServiceUpTime    Time     `xml:"serviceUpTimestamp" json:"serviceUpTimestamp"`
 }
 
 type Duration time.Duration
@@ -113,14 +136,14 @@ func (m Metadata) Equals(other Metadata) bool {
 }
 
 type App struct {
-	XMLName   xml.Name    `xml:"application"`
-	Name      string      `xml:"name"`
-	Instances []*Instance `xml:"instance"`
+	XMLName   xml.Name    `xml:"application" json:"-"`
+	Name      string      `xml:"name" json:"name"`
+	Instances []*Instance `xml:"instance" json:"instance"`
 }
 
 type AppsResponse struct {
-	XMLName      xml.Name `xml:"applications"`
-	VersionDelta int      `xml:"versions__delta"`
-	Hashcode     string   `xml:"apps__hashcode"`
-	Apps         []*App   `xml:"application"`
+	XMLName      xml.Name `xml:"applications" json:"-"`
+	VersionDelta int      `xml:"versions__delta" json:"versions__delta"`
+	Hashcode     string   `xml:"apps__hashcode" json:"apps__hashcode"`
+	Apps         []*App   `xml:"application" json:"application"`
 }
@@ -1,6 +1,8 @@
 package eureka_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"io/ioutil"
 	"path/filepath"
@@ -80,4 +82,95 @@ var _ = Describe("Instance", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(actual).To(Equal(instance))
 	})
+
+	Context("JSON", func() {
+		var instanceJson []byte
+
+		BeforeEach(func() {
+			data, err := ioutil.ReadFile(filepath.Join("fixtures", "instance.json"))
+			Expect(err).ToNot(HaveOccurred())
+
+			buf := new(bytes.Buffer)
+			Expect(json.Compact(buf, data)).To(Succeed())
+			instanceJson = buf.Bytes()
+		})
+
+		It("can be marshaled to a JSON string", func() {
+			data, err := json.Marshal(instance)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(MatchJSON(instanceJson))
+		})
+
+		It("can be unmarshaled from a JSON string", func() {
+			expected := instance
+			expected.XMLName = xml.Name{}
+
+			var actual eureka.Instance
+			err := json.Unmarshal(instanceJson, &actual)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		})
+
+		It("marks empty metadata the way Eureka does", func() {
+			data, err := json.Marshal(eureka.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(MatchJSON(`{"@class": "java.util.Collections$EmptyMap"}`))
+
+			var actual eureka.Metadata
+			Expect(json.Unmarshal(data, &actual)).To(Succeed())
+			Expect(actual).To(BeEmpty())
+		})
+
+		It("accepts ports rendered as strings or plain numbers", func() {
+			var port eureka.Port
+			Expect(json.Unmarshal([]byte(`{"$": "8080", "@enabled": true}`), &port)).To(Succeed())
+			Expect(port).To(Equal(eureka.Port(8080)))
+
+			Expect(json.Unmarshal([]byte(`8081`), &port)).To(Succeed())
+			Expect(port).To(Equal(eureka.Port(8081)))
+		})
+	})
+})
+
+var _ = Describe("Instance.Zone", func() {
+	It("uses the availability zone of the datacenter", func() {
+		i := &eureka.Instance{Metadata: eureka.Metadata{"zone": "other"}}
+		i.DataCenterInfo.Metadata.AvailabilityZone = "az"
+		Expect(i.Zone()).To(Equal("az"))
+	})
+
+	It("falls back to the zone metadata entry", func() {
+		i := &eureka.Instance{Metadata: eureka.Metadata{"zone": "zone-a"}}
+		Expect(i.Zone()).To(Equal("zone-a"))
+	})
+})
+
+var _ = Describe("AppsResponse", func() {
+	It("can be unmarshaled from Eureka's JSON representation", func() {
+		data := []byte(`{
+			"versions__delta": "7",
+			"apps__hashcode": "UP_2_",
+			"application": [
+				{"name": "ONE", "instance": {"instanceId": "a", "status": "UP"}},
+				{"name": "TWO", "instance": [{"instanceId": "b", "status": "UP"}]}
+			]
+		}`)
+
+		var actual eureka.AppsResponse
+		Expect(json.Unmarshal(data, &actual)).To(Succeed())
+		Expect(actual.VersionDelta).To(Equal(7))
+		Expect(actual.Hashcode).To(Equal("UP_2_"))
+		Expect(actual.Apps).To(HaveLen(2))
+		Expect(actual.Apps[0].Name).To(Equal("ONE"))
+		Expect(actual.Apps[0].Instances).To(HaveLen(1))
+		Expect(actual.Apps[0].Instances[0].ID).To(Equal("a"))
+		Expect(actual.Apps[1].Instances).To(HaveLen(1))
+		Expect(actual.Apps[1].Instances[0].ID).To(Equal("b"))
+	})
+
+	It("renders the versions delta as a string", func() {
+		data, err := json.Marshal(eureka.AppsResponse{VersionDelta: 3, Hashcode: "UP_1_"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"versions__delta": "3", "apps__hashcode": "UP_1_", "application": []}`))
+	})
 })
@@ -1,16 +1,33 @@
 package eureka
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/context"
+
+	"github.com/virajago/go-scs-eureka/retry"
 )
 
 // DefaultPollInterval defines the default interval at which the watcher queries
 // the registry.
 const DefaultPollInterval = 30 * time.Second
 
+// DefaultPollBackoffLimit defines the longest delay in-between polls of a
+// watcher whose polls keep failing, unless its poll interval is even longer.
+const DefaultPollBackoffLimit = 5 * time.Minute
+
+// errorBufferSize defines how many errors a watcher buffers for a consumer
+// that does not keep up. Further errors are dropped.
+const errorBufferSize = 16
+
+// ErrWatcherStopped is returned when waiting for a watcher that has been
+// stopped.
+var ErrWatcherStopped = errors.New("Watcher stopped")
+
 // EventType defines the type of an observed event.
 type EventType uint8
 
@@ -26,6 +43,32 @@ const (
 	// EventInstanceUpdated indicates that a previously registered instance has
 	// changed in the registry, e.g. status or metadata changes have been observed.
 	EventInstanceUpdated
+
+	// EventSynced indicates that the instances registered at the time of the
+	// first successful poll have been reported. Its Instance is nil. The event
+	// is only reported if enabled, see WatchSyncEvent.
+	EventSynced
+)
+
+// DeliveryPolicy defines how a watcher delivers events to a consumer that does
+// not keep up.
+type DeliveryPolicy uint8
+
+const (
+	// DeliverBlocking waits for the consumer to receive each event. Polling
+	// pauses until all events of the previous poll have been received.
+	DeliverBlocking DeliveryPolicy = iota
+
+	// DeliverDropOldest discards the oldest buffered event to make room for a
+	// new one once the buffer is full. Polling never waits for the consumer.
+	DeliverDropOldest
+
+	// DeliverCoalesce keeps only the latest pending event per instance, e.g. a
+	// pending registration followed by an update is delivered as a single
+	// registration of the updated instance. Events of the same instance are
+	// delivered in order and polling never waits for the consumer. The event
+	// that is handed to the consumer next is no longer coalesced.
+	DeliverCoalesce
 )
 
 // Event holds information about the type and subject of an observation.
@@ -34,12 +77,119 @@ type Event struct {
 	Instance *Instance
 }
 
+// WatchOption can be used to configure a Watcher.
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	apps      []string
+	vip       string
+	filter    func(*Instance) bool
+	backoff   retry.Delay
+	buffer    int
+	policy    DeliveryPolicy
+	immediate bool
+	syncEvent bool
+	handlers  []Handler
+}
+
+// WatchApps scopes the watcher to the instances of the given apps. App names
+// are matched case-insensitively. The watcher only queries these apps rather
+// than the whole registry.
+func WatchApps(appNames ...string) WatchOption {
+	return func(o *watchOptions) {
+		o.apps = append(o.apps, appNames...)
+	}
+}
+
+// WatchVIP scopes the watcher to the instances registered for the given VIP
+// address. Unless it is also scoped to apps, the watcher only queries the VIP
+// rather than the whole registry.
+func WatchVIP(vip string) WatchOption {
+	return func(o *watchOptions) {
+		o.vip = vip
+	}
+}
+
+// WatchFilter scopes the watcher to the instances matching the given
+// predicate.
+func WatchFilter(filter func(*Instance) bool) WatchOption {
+	return func(o *watchOptions) {
+		o.filter = filter
+	}
+}
+
+// WatchBackoff sets the delay in-between polls while polling fails. The delay
+// is passed the number of consecutive failures and never shortens the poll
+// interval. Defaults to an exponential backoff starting at the poll interval,
+// limited to DefaultPollBackoffLimit.
+func WatchBackoff(delay retry.Delay) WatchOption {
+	return func(o *watchOptions) {
+		o.backoff = delay
+	}
+}
+
+// WatchBuffer sets the number of events buffered for the consumer. Defaults to
+// no buffer. Events that are buffered are no longer coalesced.
+func WatchBuffer(size int) WatchOption {
+	return func(o *watchOptions) {
+		o.buffer = size
+	}
+}
+
+// WatchDelivery sets the policy for delivering events to a consumer that does
+// not keep up. Defaults to DeliverBlocking. DeliverDropOldest buffers at least
+// one event.
+func WatchDelivery(policy DeliveryPolicy) WatchOption {
+	return func(o *watchOptions) {
+		o.policy = policy
+	}
+}
+
+// WatchImmediately makes the watcher poll the registry right away rather than
+// after the first poll interval.
+func WatchImmediately() WatchOption {
+	return func(o *watchOptions) {
+		o.immediate = true
+	}
+}
+
+// WatchSyncEvent makes the watcher report an EventSynced after the events of
+// its first successful poll, which marks the end of the initial snapshot.
+func WatchSyncEvent() WatchOption {
+	return func(o *watchOptions) {
+		o.syncEvent = true
+	}
+}
+
+// WatchHandler adds a handler that is notified about every change observed by
+// the watcher. The option can be used multiple times. A watcher with handlers
+// does not send events to its Events channel. Changes pending for a handler
+// that does not keep up are coalesced like events are by DeliverCoalesce.
+func WatchHandler(handler Handler) WatchOption {
+	return func(o *watchOptions) {
+		o.handlers = append(o.handlers, handler)
+	}
+}
+
 // Watcher can be used to observe the registry for changes with respect
 // to the instances of particular app.
 type Watcher struct {
 	events    chan Event
+	errors    chan error
 	instances map[string]*Instance
 	cancel    context.CancelFunc
+	stopped   <-chan struct{}
+	synced    chan struct{}
+	options   *watchOptions
+	pending   *changeQueue
+	listeners []*listener
+
+	mtx         sync.RWMutex
+	failures    uint
+	lastErr     error
+	lastSuccess time.Time
+	dropped     uint
+	coalesced   uint
 }
 
 // Registry is being used to poll for registered Apps.
@@ -47,12 +197,58 @@ type Registry interface {
 	Apps() ([]*App, error)
 }
 
-func newWatcher(registry Registry, pollInterval time.Duration) *Watcher {
+// appRegistry and vipRegistry are implemented by registries that allow the
+// watcher to query only the apps or instances it is scoped to.
+type appRegistry interface {
+	App(appName string) (*App, error)
+}
+
+type vipRegistry interface {
+	VIP(vip string) ([]*Instance, error)
+}
+
+func newWatcher(registry Registry, pollInterval time.Duration, options ...WatchOption) *Watcher {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	watcher := &Watcher{
-		events: make(chan Event),
-		cancel: cancel,
+		errors:  make(chan error, errorBufferSize),
+		cancel:  cancel,
+		stopped: ctx.Done(),
+		synced:  make(chan struct{}),
+		options: &watchOptions{},
+	}
+
+	for _, opt := range options {
+		opt(watcher.options)
+	}
+
+	buffer := watcher.options.buffer
+	if buffer < 1 && watcher.options.policy == DeliverDropOldest {
+		buffer = 1
+	}
+	watcher.events = make(chan Event, buffer)
+
+	if watcher.options.backoff == nil {
+		limit := DefaultPollBackoffLimit
+		if pollInterval > limit {
+			limit = pollInterval
+		}
+		backoff := retry.Cap(retry.ExponentialBackoff(pollInterval), limit)
+		watcher.options.backoff = func(failures uint) time.Duration {
+			// the first failure waits a single poll interval
+			return backoff(failures - 1)
+		}
+	}
+
+	if watcher.options.policy == DeliverCoalesce {
+		watcher.pending = newChangeQueue()
+		go watcher.dispatch(ctx)
+	}
+
+	for _, h := range watcher.options.handlers {
+		l := newListener(h, watcher.report, func(n uint) { watcher.count(&watcher.coalesced, n) })
+		watcher.listeners = append(watcher.listeners, l)
+		go l.run(ctx)
 	}
 
 	go watcher.poll(ctx, registry, pollInterval)
@@ -66,62 +262,439 @@ func (w *Watcher) Stop() {
 }
 
 // Events returns a channel that can be used to listen for changes to the app
-// observed by this watcher.
+// observed by this watcher. It does not receive any events if the watcher has
+// handlers, see WatchHandler.
 func (w *Watcher) Events() <-chan Event {
 	return w.events
 }
 
+// WaitForSync blocks until the instances registered at the time of the first
+// successful poll have been reported, the context is done or the watcher is
+// stopped. Unless events are buffered or dropped, they need to be received for
+// the watcher to sync.
+func (w *Watcher) WaitForSync(ctx context.Context) error {
+	select {
+	case <-w.synced:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.stopped:
+		return ErrWatcherStopped
+	}
+}
+
+// Synced reports whether the watcher has completed its first successful poll,
+// see WaitForSync.
+func (w *Watcher) Synced() bool {
+	select {
+	case <-w.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// Errors returns a channel that reports failed polls and panicking handlers.
+// Errors are dropped if the channel is not being read from.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Failures returns the number of consecutive failed polls.
+func (w *Watcher) Failures() uint {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.failures
+}
+
+// Err returns the error of the most recent poll, if it failed.
+func (w *Watcher) Err() error {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.lastErr
+}
+
+// LastSuccessfulPoll returns the time of the last successful poll, or the zero
+// time if no poll has succeeded yet. It can be used to detect stale data.
+func (w *Watcher) LastSuccessfulPoll() time.Time {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.lastSuccess
+}
+
+// Dropped returns the number of events that have been discarded because the
+// consumer did not keep up, see DeliverDropOldest.
+func (w *Watcher) Dropped() uint {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.dropped
+}
+
+// Coalesced returns the number of events that have been merged into or
+// cancelled out by a later event of the same instance, see DeliverCoalesce and
+// WatchHandler. Changes are counted once per handler.
+func (w *Watcher) Coalesced() uint {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.coalesced
+}
+
 func (w *Watcher) poll(ctx context.Context, registry Registry, interval time.Duration) {
-	tick := time.NewTicker(interval)
-	defer tick.Stop()
+	wait := interval
+	if w.options.immediate {
+		wait = 0
+	}
 
 	for {
 		select {
-		case <-tick.C:
-			if apps, err := registry.Apps(); err == nil {
-				w.update(apps)
-			}
+		case <-time.After(wait):
 		case <-ctx.Done():
 			return
 		}
+
+		wait = interval
+
+		apps, err := w.fetch(registry)
+		if err != nil {
+			if d := w.options.backoff(w.fail(err)); d > wait {
+				wait = d
+			}
+			continue
+		}
+
+		w.succeed()
+		w.update(ctx, apps)
+
+		if !w.Synced() {
+			w.sync(ctx)
+		}
+	}
+}
+
+// sync marks the end of the initial snapshot.
+func (w *Watcher) sync(ctx context.Context) {
+	if ctx.Err() != nil {
+		// stopped while reporting the snapshot
+		return
+	}
+
+	close(w.synced)
+
+	if w.options.syncEvent {
+		w.notify(ctx, "", EventSynced, nil, nil)
 	}
 }
 
-func (w *Watcher) update(apps []*App) {
+// fail records a failed poll and returns the number of consecutive failures.
+func (w *Watcher) fail(err error) uint {
+	w.mtx.Lock()
+	w.failures++
+	w.lastErr = err
+	failures := w.failures
+	w.mtx.Unlock()
+
+	w.report(err)
+
+	return failures
+}
+
+func (w *Watcher) report(err error) {
+	select {
+	case w.errors <- err:
+	default:
+		// nobody is listening
+	}
+}
+
+func (w *Watcher) succeed() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.failures = 0
+	w.lastErr = nil
+	w.lastSuccess = time.Now()
+}
+
+// fetch queries the apps the watcher is scoped to, using the narrowest query
+// the registry supports.
+func (w *Watcher) fetch(registry Registry) ([]*App, error) {
+	if len(w.options.apps) > 0 {
+		if r, ok := registry.(appRegistry); ok {
+			return fetchApps(r, w.options.apps)
+		}
+	}
+
+	if w.options.vip != "" {
+		if r, ok := registry.(vipRegistry); ok {
+			return fetchVIP(r, w.options.vip)
+		}
+	}
+
+	return registry.Apps()
+}
+
+func fetchApps(registry appRegistry, appNames []string) ([]*App, error) {
+	apps := make([]*App, 0, len(appNames))
+	for _, name := range appNames {
+		app, err := registry.App(name)
+		if errors.Is(err, ErrNotFound) {
+			// no instances registered
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+func fetchVIP(registry vipRegistry, vip string) ([]*App, error) {
+	instances, err := registry.VIP(vip)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []*App
+	index := map[string]*App{}
+	for _, i := range instances {
+		app, found := index[i.AppName]
+		if !found {
+			app = &App{Name: i.AppName}
+			index[i.AppName] = app
+			apps = append(apps, app)
+		}
+		app.Instances = append(app.Instances, i)
+	}
+	return apps, nil
+}
+
+// matches reports whether an instance of the given app is in the scope of the
+// watcher.
+func (o *watchOptions) matches(a *App, i *Instance) bool {
+	if len(o.apps) > 0 && !containsFold(o.apps, a.Name) {
+		return false
+	}
+
+	if o.vip != "" && !containsFold(splitVIPs(i.VIPAddr), o.vip) {
+		return false
+	}
+
+	return o.filter == nil || o.filter(i)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) update(ctx context.Context, apps []*App) {
 	current := map[string]*Instance{}
 
 	// check if instances are new or have changed
 	for _, a := range apps {
 		for _, i := range a.Instances {
+			if !w.options.matches(a, i) {
+				continue
+			}
+
 			key := key(a, i)
 			current[key] = i
 
 			prev, found := w.instances[key]
 			if !found {
-				w.notify(EventInstanceRegistered, i)
+				w.notify(ctx, key, EventInstanceRegistered, nil, i)
 				continue
 			}
 
 			delete(w.instances, key)
 
 			if !i.Equals(prev) {
-				w.notify(EventInstanceUpdated, i)
+				w.notify(ctx, key, EventInstanceUpdated, prev, i)
 			}
 		}
 	}
 
 	// instances we haven't deleted above are not registered anymore
-	for _, i := range w.instances {
-		w.notify(EventInstanceDeregistered, i)
+	for key, i := range w.instances {
+		w.notify(ctx, key, EventInstanceDeregistered, i, nil)
 	}
 
 	// reset instances
 	w.instances = current
 }
 
-func (w *Watcher) notify(t EventType, i *Instance) {
-	// blocking
-	w.events <- Event{t, i}
+// notify reports a change of the instance with the given key. old is nil for
+// newly registered instances, new is nil for deregistered ones.
+func (w *Watcher) notify(ctx context.Context, key string, t EventType, old, new *Instance) {
+	c := change{t, old, new}
+
+	if len(w.listeners) > 0 {
+		for _, l := range w.listeners {
+			l.push(key, c)
+		}
+		return
+	}
+
+	e := c.event()
+
+	switch w.options.policy {
+	case DeliverDropOldest:
+		for {
+			select {
+			case w.events <- e:
+				return
+			default:
+			}
+
+			// make room, unless the consumer just did
+			select {
+			case <-w.events:
+				w.count(&w.dropped, 1)
+			default:
+			}
+		}
+
+	case DeliverCoalesce:
+		w.count(&w.coalesced, w.pending.push(key, c))
+
+	default:
+		select {
+		case w.events <- e:
+		case <-ctx.Done():
+			// stopped while waiting for the consumer
+		}
+	}
+}
+
+func (w *Watcher) count(counter *uint, n uint) {
+	if n == 0 {
+		return
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	*counter += n
+}
+
+// dispatch delivers coalesced events until the watcher is stopped.
+func (w *Watcher) dispatch(ctx context.Context) {
+	for {
+		c, ok := w.pending.pop()
+		if !ok {
+			select {
+			case <-w.pending.ready:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case w.events <- c.event():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// changeQueue holds at most one pending change per instance key, in the order
+// the instances first became pending.
+type changeQueue struct {
+	mtx     sync.Mutex
+	keys    []string
+	changes map[string]change
+	ready   chan struct{}
+}
+
+func newChangeQueue() *changeQueue {
+	return &changeQueue{
+		changes: map[string]change{},
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// push adds a change to the queue and returns the number of changes that have
+// been coalesced in the process.
+func (q *changeQueue) push(key string, c change) uint {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	prev, found := q.changes[key]
+	if !found {
+		q.keys = append(q.keys, key)
+		q.changes[key] = c
+
+		select {
+		case q.ready <- struct{}{}:
+		default:
+			// already signalled
+		}
+		return 0
+	}
+
+	merged, ok := coalesce(prev, c)
+	if !ok {
+		// the changes cancel each other out
+		delete(q.changes, key)
+		for n, k := range q.keys {
+			if k == key {
+				q.keys = append(q.keys[:n], q.keys[n+1:]...)
+				break
+			}
+		}
+		return 2
+	}
+
+	q.changes[key] = merged
+	return 1
+}
+
+func (q *changeQueue) pop() (change, bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if len(q.keys) == 0 {
+		return change{}, false
+	}
+
+	key := q.keys[0]
+	q.keys = q.keys[1:]
+
+	c := q.changes[key]
+	delete(q.changes, key)
+
+	return c, true
+}
+
+// coalesce merges a pending change with a later change of the same instance.
+// It returns false if the consumer does not need to know about either change.
+func coalesce(prev, next change) (change, bool) {
+	switch {
+	case prev.eventType == EventInstanceRegistered && next.eventType == EventInstanceDeregistered:
+		// never seen by the consumer
+		return change{}, false
+	case prev.eventType == EventInstanceRegistered:
+		return change{EventInstanceRegistered, nil, next.new}, true
+	case prev.eventType == EventInstanceDeregistered && next.eventType == EventInstanceRegistered:
+		// still known to the consumer
+		return change{EventInstanceUpdated, prev.old, next.new}, true
+	case prev.eventType == EventInstanceUpdated && next.eventType == EventInstanceUpdated:
+		// the consumer only knows the instance before the first update
+		return change{EventInstanceUpdated, prev.old, next.new}, true
+	default:
+		return next, true
+	}
 }
 
 func key(a *App, i *Instance) string {
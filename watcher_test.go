@@ -1,11 +1,16 @@
 package eureka
 
 import (
+	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+
+	"github.com/virajago/go-scs-eureka/retry"
 )
 
 var _ = Describe("Watcher", func() {
@@ -124,17 +129,389 @@ var _ = Describe("Watcher", func() {
 	})
 })
 
+var _ = Describe("Watcher scope", func() {
+	var (
+		interval = 10 * time.Millisecond
+
+		registry *mockRegistry
+		watcher  *Watcher
+
+		one, two, other *Instance
+	)
+
+	BeforeEach(func() {
+		one = &Instance{ID: "one", AppName: "FOO", VIPAddr: "foo.vip,shared.vip"}
+		two = &Instance{ID: "two", AppName: "FOO", VIPAddr: "foo.vip"}
+		other = &Instance{ID: "other", AppName: "BAR", VIPAddr: "shared.vip"}
+
+		registry = newMockRegistry()
+		registry.Register(&App{Name: "FOO", Instances: []*Instance{one, two}})
+		registry.Register(&App{Name: "BAR", Instances: []*Instance{other}})
+	})
+
+	AfterEach(func() {
+		watcher.Stop()
+	})
+
+	receiveAll := func(n int) []*Instance {
+		var instances []*Instance
+		for len(instances) < n {
+			var e Event
+			Eventually(watcher.Events()).Should(Receive(&e))
+			instances = append(instances, e.Instance)
+		}
+		Consistently(watcher.Events(), 5*interval).ShouldNot(Receive())
+		return instances
+	}
+
+	Describe("WatchApps", func() {
+		It("reports only instances of the given apps", func() {
+			watcher = newWatcher(registry, interval, WatchApps("foo"))
+			Expect(receiveAll(2)).To(ConsistOf(one, two))
+		})
+
+		It("queries only the given apps", func() {
+			watcher = newWatcher(registry, interval, WatchApps("foo", "unknown"))
+			receiveAll(2)
+
+			Expect(registry.Calls("Apps")).To(BeZero())
+			Expect(registry.Calls("App")).To(BeNumerically(">", 0))
+		})
+
+		It("falls back to querying all apps", func() {
+			watcher = newWatcher(appsOnly{registry}, interval, WatchApps("foo"))
+			Expect(receiveAll(2)).To(ConsistOf(one, two))
+		})
+	})
+
+	Describe("WatchVIP", func() {
+		It("reports only instances registered for the VIP", func() {
+			watcher = newWatcher(registry, interval, WatchVIP("shared.vip"))
+			Expect(receiveAll(2)).To(ConsistOf(one, other))
+
+			Expect(registry.Calls("Apps")).To(BeZero())
+			Expect(registry.Calls("VIP")).To(BeNumerically(">", 0))
+		})
+
+		It("can be combined with WatchApps", func() {
+			watcher = newWatcher(registry, interval, WatchApps("foo"), WatchVIP("shared.vip"))
+			Expect(receiveAll(1)).To(ConsistOf(one))
+		})
+
+		It("falls back to querying all apps", func() {
+			watcher = newWatcher(appsOnly{registry}, interval, WatchVIP("foo.vip"))
+			Expect(receiveAll(2)).To(ConsistOf(one, two))
+		})
+	})
+
+	Describe("WatchFilter", func() {
+		It("reports only instances matching the predicate", func() {
+			watcher = newWatcher(registry, interval, WatchFilter(func(i *Instance) bool {
+				return i.ID != "two"
+			}))
+			Expect(receiveAll(2)).To(ConsistOf(one, other))
+		})
+	})
+})
+
+var _ = Describe("Watcher errors", func() {
+	var (
+		interval = 10 * time.Millisecond
+		someErr  = errors.New("some error")
+
+		registry *mockRegistry
+		watcher  *Watcher
+	)
+
+	BeforeEach(func() {
+		registry = newMockRegistry()
+		registry.Fail(someErr)
+	})
+
+	AfterEach(func() {
+		watcher.Stop()
+	})
+
+	It("reports failed polls", func() {
+		watcher = newWatcher(registry, interval, WatchBackoff(retry.NoDelay()))
+
+		Eventually(watcher.Errors()).Should(Receive(Equal(someErr)))
+		Eventually(watcher.Failures).Should(BeNumerically(">=", 2))
+		Expect(watcher.Err()).To(Equal(someErr))
+		Expect(watcher.LastSuccessfulPoll()).To(BeZero())
+	})
+
+	It("resets the failure count once polling succeeds", func() {
+		watcher = newWatcher(registry, interval, WatchBackoff(retry.NoDelay()))
+		Eventually(watcher.Failures).Should(BeNumerically(">", 0))
+
+		registry.Fail(nil)
+
+		Eventually(watcher.Failures).Should(BeZero())
+		Expect(watcher.Err()).ToNot(HaveOccurred())
+		Expect(watcher.LastSuccessfulPoll()).ToNot(BeZero())
+	})
+
+	It("backs off while polling fails", func() {
+		watcher = newWatcher(registry, interval, WatchBackoff(retry.ConstantDelay(time.Hour)))
+
+		Eventually(watcher.Failures).Should(Equal(uint(1)))
+		Consistently(watcher.Failures, 10*interval).Should(Equal(uint(1)))
+	})
+
+	It("backs off exponentially by default, starting at the poll interval", func() {
+		watcher = newWatcher(registry, time.Second)
+
+		Expect(watcher.options.backoff(1)).To(Equal(time.Second))
+		Expect(watcher.options.backoff(2)).To(Equal(2 * time.Second))
+		Expect(watcher.options.backoff(3)).To(Equal(4 * time.Second))
+		Expect(watcher.options.backoff(100)).To(Equal(DefaultPollBackoffLimit))
+	})
+
+	It("does not block if errors are not consumed", func() {
+		watcher = newWatcher(registry, time.Millisecond, WatchBackoff(retry.NoDelay()))
+		Eventually(watcher.Failures).Should(BeNumerically(">", errorBufferSize+5))
+	})
+})
+
+var _ = Describe("Watcher delivery", func() {
+	var (
+		interval = 10 * time.Millisecond
+
+		registry *mockRegistry
+		watcher  *Watcher
+	)
+
+	BeforeEach(func() {
+		registry = newMockRegistry()
+	})
+
+	AfterEach(func() {
+		watcher.Stop()
+	})
+
+	instance := func(id, hostName string) *Instance {
+		return &Instance{ID: id, HostName: hostName}
+	}
+
+	// register replaces the instances of the watched app and waits for the
+	// watcher to observe the change
+	register := func(instances ...*Instance) {
+		calls := registry.Calls("Apps")
+		registry.Register(&App{Name: "app", Instances: instances})
+		Eventually(func() int { return registry.Calls("Apps") }).Should(BeNumerically(">", calls+1))
+	}
+
+	Describe("DeliverBlocking", func() {
+		It("stops waiting for the consumer when stopped", func() {
+			watcher = newWatcher(registry, interval)
+			registry.Register(&App{Name: "app", Instances: []*Instance{
+				instance("one", "one.example.com"),
+				instance("two", "two.example.com"),
+			}})
+			Eventually(func() int { return registry.Calls("Apps") }).Should(Equal(1))
+
+			watcher.Stop()
+			Consistently(watcher.Events(), 5*interval).ShouldNot(Receive())
+		})
+
+		It("buffers events", func() {
+			watcher = newWatcher(registry, interval, WatchBuffer(2))
+			register(instance("one", "one.example.com"), instance("two", "two.example.com"))
+
+			Expect(watcher.Events()).To(HaveLen(2))
+		})
+	})
+
+	Describe("DeliverDropOldest", func() {
+		It("keeps polling and drops the oldest events", func() {
+			one := instance("one", "one.example.com")
+			two := instance("two", "two.example.com")
+			three := instance("three", "three.example.com")
+
+			watcher = newWatcher(registry, interval, WatchDelivery(DeliverDropOldest))
+			register(one, two, three)
+
+			Expect(watcher.Dropped()).To(Equal(uint(2)))
+			Expect(watcher.Events()).To(Receive(Equal(Event{EventInstanceRegistered, three})))
+
+			register(one, two)
+			Eventually(watcher.Events()).Should(Receive(Equal(Event{EventInstanceDeregistered, three})))
+		})
+	})
+
+	Describe("DeliverCoalesce", func() {
+		BeforeEach(func() {
+			watcher = newWatcher(registry, interval, WatchDelivery(DeliverCoalesce))
+
+			// handed to the consumer next, i.e. not coalesced
+			register(instance("next", "next.example.com"))
+		})
+
+		AfterEach(func() {
+			Consistently(watcher.Events(), 5*interval).ShouldNot(Receive())
+		})
+
+		receive := func(expected ...Event) {
+			Eventually(watcher.Events()).Should(Receive(Equal(Event{EventInstanceRegistered, instance("next", "next.example.com")})))
+			for _, e := range expected {
+				Eventually(watcher.Events()).Should(Receive(Equal(e)))
+			}
+		}
+
+		It("delivers only the latest update of an instance", func() {
+			register(instance("next", "next.example.com"), instance("one", "one.example.com"))
+			receive(Event{EventInstanceRegistered, instance("one", "one.example.com")})
+
+			// handed to the consumer next
+			register(instance("next", "next.example.com"), instance("one", "two.example.com"))
+
+			register(instance("next", "next.example.com"), instance("one", "three.example.com"))
+			register(instance("next", "next.example.com"), instance("one", "four.example.com"))
+
+			Expect(watcher.Coalesced()).To(Equal(uint(1)))
+			Eventually(watcher.Events()).Should(Receive(Equal(Event{EventInstanceUpdated, instance("one", "two.example.com")})))
+			Eventually(watcher.Events()).Should(Receive(Equal(Event{EventInstanceUpdated, instance("one", "four.example.com")})))
+		})
+
+		It("delivers a new instance as registered in its latest state", func() {
+			register(instance("next", "next.example.com"), instance("one", "one.example.com"))
+			register(instance("next", "next.example.com"), instance("one", "two.example.com"))
+
+			Expect(watcher.Coalesced()).To(Equal(uint(1)))
+			receive(Event{EventInstanceRegistered, instance("one", "two.example.com")})
+		})
+
+		It("drops events that cancel each other out", func() {
+			register(instance("next", "next.example.com"), instance("one", "one.example.com"))
+			register(instance("next", "next.example.com"))
+
+			Expect(watcher.Coalesced()).To(Equal(uint(2)))
+			receive()
+		})
+
+		It("delivers a re-registered instance as updated", func() {
+			register(instance("next", "next.example.com"), instance("one", "one.example.com"))
+			receive(Event{EventInstanceRegistered, instance("one", "one.example.com")})
+
+			// handed to the consumer next
+			register(instance("next", "next.example.com"), instance("one", "one.example.com"), instance("two", "two.example.com"))
+
+			register(instance("next", "next.example.com"), instance("two", "two.example.com"))
+			register(instance("next", "next.example.com"), instance("one", "new.example.com"), instance("two", "two.example.com"))
+
+			Expect(watcher.Coalesced()).To(Equal(uint(1)))
+			Eventually(watcher.Events()).Should(Receive(Equal(Event{EventInstanceRegistered, instance("two", "two.example.com")})))
+			Eventually(watcher.Events()).Should(Receive(Equal(Event{EventInstanceUpdated, instance("one", "new.example.com")})))
+		})
+	})
+})
+
+var _ = Describe("Watcher sync", func() {
+	var (
+		interval = 10 * time.Millisecond
+		one      = &Instance{ID: "one", HostName: "one.example.com"}
+		two      = &Instance{ID: "two", HostName: "two.example.com"}
+
+		registry *mockRegistry
+		watcher  *Watcher
+	)
+
+	BeforeEach(func() {
+		registry = newMockRegistry()
+		registry.Register(&App{Name: "app", Instances: []*Instance{one, two}})
+	})
+
+	AfterEach(func() {
+		watcher.Stop()
+	})
+
+	It("polls right away if asked to", func() {
+		watcher = newWatcher(registry, time.Hour, WatchImmediately())
+		Eventually(watcher.Events()).Should(Receive())
+	})
+
+	It("waits for the initial snapshot to be reported", func() {
+		watcher = newWatcher(registry, interval, WatchBuffer(2))
+		Expect(watcher.Synced()).To(BeFalse())
+
+		Expect(watcher.WaitForSync(context.Background())).To(Succeed())
+		Expect(watcher.Synced()).To(BeTrue())
+		Expect(watcher.Events()).To(HaveLen(2))
+	})
+
+	It("does not sync until a poll succeeds", func() {
+		registry.Fail(errors.New("some error"))
+		watcher = newWatcher(registry, interval, WatchBackoff(retry.NoDelay()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*interval)
+		defer cancel()
+
+		Expect(watcher.WaitForSync(ctx)).To(Equal(context.DeadlineExceeded))
+		Expect(watcher.Synced()).To(BeFalse())
+	})
+
+	It("stops waiting once the watcher is stopped", func() {
+		watcher = newWatcher(registry, time.Hour)
+		watcher.Stop()
+
+		Expect(watcher.WaitForSync(context.Background())).To(Equal(ErrWatcherStopped))
+	})
+
+	It("reports the end of the initial snapshot if asked to", func() {
+		watcher = newWatcher(registry, interval, WatchSyncEvent())
+
+		var events []Event
+		for len(events) < 2 {
+			var e Event
+			Eventually(watcher.Events()).Should(Receive(&e))
+			events = append(events, e)
+		}
+		Expect(events).To(ConsistOf(Event{EventInstanceRegistered, one}, Event{EventInstanceRegistered, two}))
+
+		Eventually(watcher.Events()).Should(Receive(Equal(Event{Type: EventSynced})))
+		Expect(watcher.Synced()).To(BeTrue())
+
+		registry.Register(&App{Name: "app", Instances: []*Instance{one}})
+		Eventually(watcher.Events()).Should(Receive(Equal(Event{EventInstanceDeregistered, two})))
+		Consistently(watcher.Events(), 5*interval).ShouldNot(Receive())
+	})
+})
+
+// appsOnly hides all methods of a registry but Apps.
+type appsOnly struct {
+	Registry
+}
+
 type mockRegistry struct {
-	mtx  sync.RWMutex
-	apps map[string]*App
+	mtx   sync.RWMutex
+	apps  map[string]*App
+	calls map[string]int
+	err   error
 }
 
 func newMockRegistry() *mockRegistry {
 	return &mockRegistry{
-		apps: map[string]*App{},
+		apps:  map[string]*App{},
+		calls: map[string]int{},
 	}
 }
 
+// Fail makes all queries fail with the given error, or succeed if err is nil.
+func (m *mockRegistry) Fail(err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.err = err
+}
+
+func (m *mockRegistry) Calls(method string) int {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.calls[method]
+}
+
 func (m *mockRegistry) Register(app *App) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
@@ -150,8 +527,13 @@ func (m *mockRegistry) Deregister(name string) {
 }
 
 func (m *mockRegistry) Apps() ([]*App, error) {
-	m.mtx.RLock()
-	defer m.mtx.RUnlock()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.calls["Apps"]++
+	if m.err != nil {
+		return nil, m.err
+	}
 
 	apps := make([]*App, 0, len(m.apps))
 	for _, a := range m.apps {
@@ -160,3 +542,42 @@ func (m *mockRegistry) Apps() ([]*App, error) {
 
 	return apps, nil
 }
+
+func (m *mockRegistry) App(appName string) (*App, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.calls["App"]++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, a := range m.apps {
+		if strings.EqualFold(a.Name, appName) {
+			return a, nil
+		}
+	}
+
+	return nil, &HTTPError{StatusCode: 404}
+}
+
+func (m *mockRegistry) VIP(vip string) ([]*Instance, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.calls["VIP"]++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var instances []*Instance
+	for _, a := range m.apps {
+		for _, i := range a.Instances {
+			if containsFold(splitVIPs(i.VIPAddr), vip) {
+				instances = append(instances, i)
+			}
+		}
+	}
+
+	return instances, nil
+}